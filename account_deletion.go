@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// ACCOUNT_DELETION_POLICY decides what happens to a deleted user's chirps:
+// delete removes them with the account, leaving tombstones where others
+// replied, and anonymize keeps them under a scrubbed placeholder account.
+const (
+	deletionPolicyDelete    = "delete"
+	deletionPolicyAnonymize = "anonymize"
+)
+
+// deleteAccount removes user according to the configured policy. Sessions
+// and API tokens are revoked either way. The database changes are made in
+// one transaction so a failure leaves the account as it was; media files are
+// only deleted once it has committed.
+func deleteAccount(r *http.Request, user database.User) error {
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := apiCfg.dbQueries.WithTx(tx)
+
+	err = queries.RevokeUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	err = queries.RevokeUserAPITokens(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	var uploads []database.MediaUpload
+	rowdeleted := false
+	if apiCfg.deletionpolicy == deletionPolicyAnonymize {
+		uploads, err = anonymizeAccount(r.Context(), queries, user)
+	} else {
+		uploads, rowdeleted, err = purgeAccount(r.Context(), queries, user)
+	}
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	deleteMediaFiles(r.Context(), uploads)
+
+	// Once the row is gone the event is kept without a user and names it in
+	// the detail instead.
+	eventuserid := user.ID
+	if rowdeleted {
+		eventuserid = uuid.Nil
+	}
+	recordSecurityEvent(r.Context(), r, eventuserid, "account_deleted", "user "+user.ID.String()+" deleted with policy "+apiCfg.deletionpolicy)
+	return nil
+}
+
+// purgeAccount deletes user and their chirps, reporting whether the user row
+// itself went. Deleting the row would cascade to every chirp and cut other
+// users' replies loose from their threads, so chirps that were replied to
+// are tombstoned instead and a scrubbed row is kept to own them.
+func purgeAccount(ctx context.Context, queries *database.Queries, user database.User) ([]database.MediaUpload, bool, error) {
+	kept, err := queries.TombstoneRepliedUserChirps(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if kept == 0 {
+		uploads, err := queries.ListMediaByUserID(ctx, user.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		return uploads, true, queries.DeleteUser(ctx, user.ID)
+	}
+
+	err = queries.DeleteUntombstonedUserChirps(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	err = queries.DeleteUserLikes(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	uploads, err := queries.DeleteUserMedia(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = anonymizeAccount(ctx, queries, user)
+	return uploads, false, err
+}
+
+// anonymizeAccount scrubs everything that identifies user but leaves the
+// row and its chirps in place. It returns the uploads it removed, which are
+// the ones never attached to a chirp.
+func anonymizeAccount(ctx context.Context, queries *database.Queries, user database.User) ([]database.MediaUpload, error) {
+	err := queries.AnonymizeUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = queries.DeleteRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = queries.DeleteUserEmailTokens(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = queries.DeleteUserFollows(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return queries.DeleteUnattachedMedia(ctx, user.ID)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func adminDeleteFilterHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	filteruuid, err := uuid.Parse(r.PathValue("filterID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid filter ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	deleted, err := apiCfg.dbQueries.DeleteFilter(r.Context(), filteruuid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if deleted == 0 {
+		errdres := errorResponse{Error: "Filter not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	err = apiCfg.reloadFilters(r.Context())
+	if err != nil {
+		errdres := errorResponse{Error: "Filter deleted but not reloaded"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	deleteres := successResponse{
+		Message: "Filter deleted",
+	}
+	resjson, _ := json.Marshal(deleteres)
+	w.WriteHeader(204)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -1,10 +1,60 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/pagination"
+
+	"github.com/google/uuid"
 )
 
+// adminUserResponse is what operators see of an account, including the
+// email and moderation state that public responses leave out.
+type adminUserResponse struct {
+	Id               string `json:"id"`
+	CreatedAt        string `json:"created_at"`
+	UpdatedAt        string `json:"updated_at"`
+	Email            string `json:"email"`
+	EmailVerified    bool   `json:"email_verified"`
+	Handle           string `json:"handle"`
+	DisplayName      string `json:"display_name"`
+	Role             string `json:"role"`
+	Red              bool   `json:"is_chirpy_red"`
+	TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	Suspended        bool   `json:"suspended"`
+	SuspendedAt      string `json:"suspended_at,omitempty"`
+	SuspendedReason  string `json:"suspended_reason,omitempty"`
+	Deleted          bool   `json:"deleted"`
+}
+
+func adminUserResponseFor(user database.User) adminUserResponse {
+	res := adminUserResponse{
+		Id:               user.ID.String(),
+		CreatedAt:        user.CreatedAt.String(),
+		UpdatedAt:        user.UpdatedAt.String(),
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		Handle:           user.Handle,
+		DisplayName:      user.DisplayName,
+		Role:             user.Role,
+		Red:              user.IsChirpyRed.Bool,
+		TwoFactorEnabled: user.TotpEnabled,
+		Suspended:        user.SuspendedAt.Valid,
+		SuspendedReason:  user.SuspendedReason,
+		Deleted:          user.DeletedAt.Valid,
+	}
+	if user.SuspendedAt.Valid {
+		res.SuspendedAt = user.SuspendedAt.Time.String()
+	}
+	return res
+}
+
 func adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 	w.WriteHeader(http.StatusOK)
@@ -17,3 +67,291 @@ func adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
 </html>`, apiCfg.fileserverHits.Load())
 	w.Write([]byte(res))
 }
+
+func adminListFiltersHandler(w http.ResponseWriter, r *http.Request) {
+	type filterResponse struct {
+		Id        string `json:"id"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+		Word      string `json:"word"`
+		WholeWord bool   `json:"whole_word"`
+		Action    string `json:"action"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	filters, err := apiCfg.dbQueries.ListFilters(r.Context())
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := []filterResponse{}
+	for _, f := range filters {
+		res = append(res, filterResponse{
+			Id:        f.ID.String(),
+			CreatedAt: f.CreatedAt.String(),
+			UpdatedAt: f.UpdatedAt.String(),
+			Word:      f.Word,
+			WholeWord: f.WholeWord,
+			Action:    f.Action,
+		})
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminGetChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type adminChirpResponse struct {
+		chirpResponse
+		OriginalBody string `json:"original_body,omitempty"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	chirpuuid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid chirp ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
+	if err != nil {
+		errdres := errorResponse{Error: "Chirp not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirpres, err := chirpResponseFor(r.Context(), chirp, roleUserID(r))
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := adminChirpResponse{
+		chirpResponse: chirpres,
+		OriginalBody:  chirp.OriginalBody.String,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	type listResponse struct {
+		Users      []adminUserResponse `json:"users"`
+		NextCursor string              `json:"next_cursor,omitempty"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	var email sql.NullString
+	var cursorCreatedAt sql.NullTime
+	var cursorID uuid.NullUUID
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	cursorparam := r.URL.Query().Get("cursor")
+	if len(cursorparam) > 0 {
+		cursor, err := pagination.DecodeCursor(cursorparam)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid cursor"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		cursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	// The search is a substring match, so LIKE wildcards in it are escaped.
+	if emailparam := strings.TrimSpace(r.URL.Query().Get("email")); len(emailparam) > 0 {
+		escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+		email = sql.NullString{String: escaper.Replace(emailparam), Valid: true}
+	}
+
+	users, err := apiCfg.dbQueries.ListUsers(r.Context(), database.ListUsersParams{
+		Email:           email,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := listResponse{Users: []adminUserResponse{}}
+	if len(users) > int(limit) {
+		users = users[:limit]
+		last := users[len(users)-1]
+		res.NextCursor = pagination.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	for _, user := range users {
+		res.Users = append(res.Users, adminUserResponseFor(user))
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminGetUserHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	useruuid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid user ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), useruuid)
+	if errors.Is(err, sql.ErrNoRows) {
+		errdres := errorResponse{Error: "User not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(adminUserResponseFor(user))
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+// adminReportQueueHandler lists chirps with open reports, most reported
+// first.
+func adminReportQueueHandler(w http.ResponseWriter, r *http.Request) {
+	type queueEntry struct {
+		ChirpID         string   `json:"chirp_id"`
+		AuthorID        string   `json:"author_id"`
+		Body            string   `json:"body"`
+		Hidden          bool     `json:"hidden"`
+		ReportCount     int64    `json:"report_count"`
+		Reasons         []string `json:"reasons"`
+		FirstReportedAt string   `json:"first_reported_at"`
+		LastReportedAt  string   `json:"last_reported_at"`
+	}
+	type queueResponse struct {
+		Reports    []queueEntry `json:"reports"`
+		NextCursor string       `json:"next_cursor,omitempty"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	var offset int32
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Resolving reports reorders the queue, so it pages by offset.
+	cursorparam := r.URL.Query().Get("cursor")
+	if len(cursorparam) > 0 {
+		offset, err = pagination.DecodeOffset(cursorparam)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid cursor"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+
+	entries, err := apiCfg.dbQueries.ListReportQueue(r.Context(), database.ListReportQueueParams{
+		Limit:  limit + 1,
+		Offset: offset,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := queueResponse{Reports: []queueEntry{}}
+	if len(entries) > int(limit) {
+		entries = entries[:limit]
+		res.NextCursor = pagination.EncodeOffset(offset + limit)
+	}
+	for _, entry := range entries {
+		res.Reports = append(res.Reports, queueEntry{
+			ChirpID:         entry.ChirpID.String(),
+			AuthorID:        entry.AuthorID.String(),
+			Body:            entry.Body,
+			Hidden:          entry.HiddenAt.Valid,
+			ReportCount:     entry.ReportCount,
+			Reasons:         strings.Split(entry.Reasons, ","),
+			FirstReportedAt: entry.FirstReportedAt.String(),
+			LastReportedAt:  entry.LastReportedAt.String(),
+		})
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -1,7 +1,18 @@
 package main
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/filter"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func metricsReset(w http.ResponseWriter, r *http.Request) {
@@ -20,3 +31,474 @@ func metricsReset(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	apiCfg.fileserverHits.Store(0)
 }
+
+func adminCreateFilterHandler(w http.ResponseWriter, r *http.Request) {
+	type filterRequest struct {
+		Word      string `json:"word"`
+		WholeWord bool   `json:"whole_word"`
+		Action    string `json:"action"`
+	}
+
+	type filterResponse struct {
+		Id        string `json:"id"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+		Word      string `json:"word"`
+		WholeWord bool   `json:"whole_word"`
+		Action    string `json:"action"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	filterrequest := filterRequest{}
+	err := decoder.Decode(&filterrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	filterrequest.Word = strings.TrimSpace(filterrequest.Word)
+	if len(filterrequest.Action) == 0 {
+		filterrequest.Action = filter.ActionReplace
+	}
+	if len(filterrequest.Word) == 0 || (filterrequest.Action != filter.ActionReplace && filterrequest.Action != filter.ActionReject) {
+		errdres := errorResponse{Error: "Invalid filter"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	created, err := apiCfg.dbQueries.CreateFilter(r.Context(), database.CreateFilterParams{
+		Word:      filterrequest.Word,
+		WholeWord: filterrequest.WholeWord,
+		Action:    filterrequest.Action,
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		errdres := errorResponse{Error: "Filter already exists"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	err = apiCfg.reloadFilters(r.Context())
+	if err != nil {
+		errdres := errorResponse{Error: "Filter saved but not loaded"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := filterResponse{
+		Id:        created.ID.String(),
+		CreatedAt: created.CreatedAt.String(),
+		UpdatedAt: created.UpdatedAt.String(),
+		Word:      created.Word,
+		WholeWord: created.WholeWord,
+		Action:    created.Action,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(201)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminUnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.ClearLoginFailures(r.Context(), []string{
+		accountThrottleKey(user.Email),
+		twoFactorThrottleKey(user.ID),
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	recordSecurityEvent(r.Context(), r, user.ID, "account_unlocked", "login lockout cleared by admin")
+
+	res := successResponse{Message: "Account unlocked for user " + user.ID.String()}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+// adminTargetUser loads the account named by the userID path value, writing
+// the error response itself when there is none to act on.
+func adminTargetUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	useruuid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid user ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return database.User{}, false
+	}
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), useruuid)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.DeletedAt.Valid) {
+		errdres := errorResponse{Error: "User not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return database.User{}, false
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func adminSuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	type suspendRequest struct {
+		Reason string `json:"reason"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	suspendrequest := suspendRequest{}
+	err := decoder.Decode(&suspendrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	adminid := roleUserID(r)
+	if user.ID == adminid {
+		errdres := errorResponse{Error: "Admins cannot suspend themselves"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Refresh tokens go too, so lifting the suspension still needs a new
+	// login. API tokens are only blocked and work again afterwards.
+	suspended, err := apiCfg.dbQueries.SuspendUser(r.Context(), database.SuspendUserParams{
+		ID:              user.ID,
+		SuspendedReason: strings.TrimSpace(suspendrequest.Reason),
+	})
+	if err == nil {
+		err = apiCfg.dbQueries.RevokeUserRefreshTokens(r.Context(), user.ID)
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	recordSecurityEvent(r.Context(), r, user.ID, "account_suspended", "by "+adminid.String()+": "+suspended.SuspendedReason)
+
+	resjson, _ := json.Marshal(adminUserResponseFor(suspended))
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminUnsuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	unsuspended, err := apiCfg.dbQueries.UnsuspendUser(r.Context(), user.ID)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	recordSecurityEvent(r.Context(), r, user.ID, "account_unsuspended", "by "+roleUserID(r).String())
+
+	resjson, _ := json.Marshal(adminUserResponseFor(unsuspended))
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+// adminForcePasswordResetHandler clears the password, so neither the old
+// one nor any session keeps working, and mails the user a reset link.
+func adminForcePasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := apiCfg.dbQueries.ClearUserPassword(r.Context(), user.ID)
+	if err == nil {
+		err = revokeSessionsForPasswordChange(r, user.ID)
+	}
+	if err == nil {
+		err = apiCfg.dbQueries.ExpireEmailTokens(r.Context(), database.ExpireEmailTokensParams{
+			UserID:  user.ID,
+			Purpose: purposePasswordReset,
+		})
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	recordSecurityEvent(r.Context(), r, user.ID, "password_reset_forced", "by "+roleUserID(r).String())
+
+	err = sendEmailToken(r.Context(), user, purposePasswordReset)
+	if err != nil {
+		errdres := errorResponse{Error: "Password cleared but reset email not sent"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Password reset required for user " + user.ID.String()}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminLogoutUserHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	err := apiCfg.dbQueries.RevokeUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	recordSecurityEvent(r.Context(), r, user.ID, "sessions_revoked", "all sessions revoked by "+roleUserID(r).String())
+
+	res := successResponse{Message: "All sessions revoked for user " + user.ID.String()}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+// adminModerateChirpHandler applies a moderator's decision to a reported
+// chirp, closes its open reports and records the action.
+func adminModerateChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type moderateRequest struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	type moderateResponse struct {
+		ChirpID         string `json:"chirp_id"`
+		Action          string `json:"action"`
+		ResolvedReports int64  `json:"resolved_reports"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	chirpuuid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid chirp ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	moderaterequest := moderateRequest{}
+	err = decoder.Decode(&moderaterequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	moderaterequest.Note = strings.TrimSpace(moderaterequest.Note)
+	switch moderaterequest.Action {
+	case moderationDismiss, moderationHide, moderationDelete, moderationSuspendAuthor:
+	default:
+		errdres := errorResponse{Error: "Invalid action"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
+	if err != nil || chirp.TombstonedAt.Valid {
+		errdres := errorResponse{Error: "Chirp not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	moderatorid := roleUserID(r)
+
+	switch moderaterequest.Action {
+	case moderationHide:
+		err = apiCfg.dbQueries.HideChirp(r.Context(), chirp.ID)
+		// Its tags would otherwise keep counting towards trending.
+		if err == nil {
+			err = apiCfg.dbQueries.DeleteChirpTags(r.Context(), chirp.ID)
+		}
+	case moderationDelete:
+		err = removeChirp(r.Context(), chirp)
+	case moderationSuspendAuthor:
+		var moderator, author database.User
+		moderator, err = apiCfg.dbQueries.GetUserByID(r.Context(), moderatorid)
+		if err == nil {
+			author, err = apiCfg.dbQueries.GetUserByID(r.Context(), chirp.UserID)
+		}
+		if err != nil {
+			break
+		}
+		// Moderators can only suspend regular users; staff need an admin.
+		if author.ID == moderatorid || (auth.HasRole(author.Role, auth.RoleModerator) && !auth.HasRole(moderator.Role, auth.RoleAdmin)) {
+			errdres := errorResponse{Error: "Not allowed to suspend this author"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(403)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		_, err = apiCfg.dbQueries.SuspendUser(r.Context(), database.SuspendUserParams{
+			ID:              author.ID,
+			SuspendedReason: moderaterequest.Note,
+		})
+		if err == nil {
+			err = apiCfg.dbQueries.RevokeUserRefreshTokens(r.Context(), author.ID)
+		}
+		if err == nil {
+			recordSecurityEvent(r.Context(), r, author.ID, "account_suspended", "by "+moderatorid.String()+" over chirp "+chirp.ID.String()+": "+moderaterequest.Note)
+		}
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// A hard delete has already taken the reports with it; tombstones keep
+	// theirs, which are closed here like any other.
+	resolved, err := apiCfg.dbQueries.ResolveChirpReports(r.Context(), database.ResolveChirpReportsParams{
+		ChirpID:    chirp.ID,
+		Resolution: sql.NullString{String: moderaterequest.Action, Valid: true},
+	})
+	if err == nil {
+		err = apiCfg.dbQueries.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID: uuid.NullUUID{UUID: moderatorid, Valid: true},
+			ChirpID:     chirp.ID,
+			AuthorID:    uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+			Action:      moderaterequest.Action,
+			Note:        moderaterequest.Note,
+		})
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := moderateResponse{
+		ChirpID:         chirp.ID.String(),
+		Action:          moderaterequest.Action,
+		ResolvedReports: resolved,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/filter"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func adminUpdateFilterHandler(w http.ResponseWriter, r *http.Request) {
+	type filterRequest struct {
+		Word      string `json:"word"`
+		WholeWord bool   `json:"whole_word"`
+		Action    string `json:"action"`
+	}
+
+	type filterResponse struct {
+		Id        string `json:"id"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+		Word      string `json:"word"`
+		WholeWord bool   `json:"whole_word"`
+		Action    string `json:"action"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	filteruuid, err := uuid.Parse(r.PathValue("filterID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid filter ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	filterrequest := filterRequest{}
+	err = decoder.Decode(&filterrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	filterrequest.Word = strings.TrimSpace(filterrequest.Word)
+	if len(filterrequest.Action) == 0 {
+		filterrequest.Action = filter.ActionReplace
+	}
+	if len(filterrequest.Word) == 0 || (filterrequest.Action != filter.ActionReplace && filterrequest.Action != filter.ActionReject) {
+		errdres := errorResponse{Error: "Invalid filter"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	updated, err := apiCfg.dbQueries.UpdateFilter(r.Context(), database.UpdateFilterParams{
+		ID:        filteruuid,
+		Word:      filterrequest.Word,
+		WholeWord: filterrequest.WholeWord,
+		Action:    filterrequest.Action,
+	})
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) {
+		errdres := errorResponse{Error: "Filter not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		errdres := errorResponse{Error: "Filter already exists"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	err = apiCfg.reloadFilters(r.Context())
+	if err != nil {
+		errdres := errorResponse{Error: "Filter saved but not loaded"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := filterResponse{
+		Id:        updated.ID.String(),
+		CreatedAt: updated.CreatedAt.String(),
+		UpdatedAt: updated.UpdatedAt.String(),
+		Word:      updated.Word,
+		WholeWord: updated.WholeWord,
+		Action:    updated.Action,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminSetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	type roleRequest struct {
+		Role string `json:"role"`
+	}
+	type roleResponse struct {
+		Id     string `json:"id"`
+		Handle string `json:"handle"`
+		Role   string `json:"role"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	useruuid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid user ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	rolerequest := roleRequest{}
+	err = decoder.Decode(&rolerequest)
+	if err != nil || !auth.ValidRole(rolerequest.Role) {
+		errdres := errorResponse{Error: "Invalid role"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Keeping the caller an admin guarantees there is always one left.
+	adminid := roleUserID(r)
+	if useruuid == adminid && rolerequest.Role != auth.RoleAdmin {
+		errdres := errorResponse{Error: "Admins cannot demote themselves"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	user, err := apiCfg.dbQueries.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   useruuid,
+		Role: rolerequest.Role,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		errdres := errorResponse{Error: "User not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	recordSecurityEvent(r.Context(), r, user.ID, "role_changed", "role set to "+user.Role+" by "+adminid.String())
+
+	res := roleResponse{
+		Id:     user.ID.String(),
+		Handle: user.Handle,
+		Role:   user.Role,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func adminSetUserRedHandler(w http.ResponseWriter, r *http.Request) {
+	type redRequest struct {
+		IsChirpyRed *bool `json:"is_chirpy_red"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	redrequest := redRequest{}
+	err := decoder.Decode(&redrequest)
+	if err != nil || redrequest.IsChirpyRed == nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	user, ok := adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	updated, err := apiCfg.dbQueries.SetUserRed(r.Context(), database.SetUserRedParams{
+		ID:          user.ID,
+		IsChirpyRed: sql.NullBool{Bool: *redrequest.IsChirpyRed, Valid: true},
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	recordSecurityEvent(r.Context(), r, user.ID, "chirpy_red_changed", fmt.Sprintf("set to %v by %s", updated.IsChirpyRed.Bool, roleUserID(r)))
+
+	resjson, _ := json.Marshal(adminUserResponseFor(updated))
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
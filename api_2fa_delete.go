@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/throttle"
+)
+
+func disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	type disableRequest struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	disablerequest := disableRequest{}
+	err = decoder.Decode(&disablerequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if !user.TotpEnabled {
+		errdres := errorResponse{Error: "Two-factor authentication not enabled"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// A stolen access token alone must not be enough to turn 2FA off, so the
+	// password and code are checked, and throttled, like a login.
+	retryafter, err := apiCfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{accountThrottleKey(user.Email), twoFactorThrottleKey(user.ID), ipThrottleKey(r)})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if retryafter > 0 {
+		writeTooManyAttempts(w, retryafter)
+		return
+	}
+
+	err = auth.CheckPasswordHash(disablerequest.Password, user.HashedPassword)
+	if err != nil {
+		recordLoginFailure(r, user.ID, "wrong password", map[string]throttle.Policy{
+			accountThrottleKey(user.Email): accountLoginPolicy,
+			ipThrottleKey(r):               ipLoginPolicy,
+		})
+		errdres := errorResponse{Error: "Invalid password or code"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	ok, err := checkSecondFactor(r.Context(), user, disablerequest.Code)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if !ok {
+		recordLoginFailure(r, user.ID, "wrong second factor", map[string]throttle.Policy{
+			twoFactorThrottleKey(user.ID): accountLoginPolicy,
+			ipThrottleKey(r):              ipLoginPolicy,
+		})
+		errdres := errorResponse{Error: "Invalid password or code"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	err = apiCfg.dbQueries.DisableTOTP(r.Context(), userid)
+	if err == nil {
+		err = apiCfg.dbQueries.DeleteRecoveryCodes(r.Context(), userid)
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Two-factor authentication disabled"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(204)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
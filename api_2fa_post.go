@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/throttle"
+	"github.com/felixcao99/chirpy/internal/totp"
+)
+
+const (
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+func enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type enrollResponse struct {
+		Secret        string   `json:"secret"`
+		OtpauthURI    string   `json:"otpauth_uri"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if user.TotpEnabled {
+		errdres := errorResponse{Error: "Two-factor authentication already enabled"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		errdres := errorResponse{Error: "Secret not generated"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		errdres := errorResponse{Error: "Recovery codes not generated"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	var codehashes []string
+	for _, code := range codes {
+		codehash, err := auth.HashPassword(code)
+		if err != nil {
+			errdres := errorResponse{Error: "Recovery codes not generated"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		codehashes = append(codehashes, codehash)
+	}
+
+	// 2FA stays off until a code from the new secret is confirmed, so a
+	// failed scan cannot lock the user out.
+	err = apiCfg.dbQueries.SetPendingTOTPSecret(r.Context(), database.SetPendingTOTPSecretParams{
+		ID:         userid,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err == nil {
+		err = apiCfg.dbQueries.DeleteRecoveryCodes(r.Context(), userid)
+	}
+	if err == nil {
+		err = apiCfg.dbQueries.AddRecoveryCodes(r.Context(), database.AddRecoveryCodesParams{
+			UserID:     userid,
+			CodeHashes: codehashes,
+		})
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := enrollResponse{
+		Secret:        secret,
+		OtpauthURI:    totp.URI(apiCfg.jwtkeys.Issuer, user.Email, secret),
+		RecoveryCodes: codes,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(201)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	type confirmRequest struct {
+		Code string `json:"code"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	confirmrequest := confirmRequest{}
+	err = decoder.Decode(&confirmrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if user.TotpEnabled {
+		errdres := errorResponse{Error: "Two-factor authentication already enabled"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		errdres := errorResponse{Error: "Two-factor enrollment not started"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	step, ok := totp.Validate(user.TotpSecret.String, confirmrequest.Code, time.Now())
+	if !ok {
+		errdres := errorResponse{Error: "Invalid code"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	err = apiCfg.dbQueries.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		ID:           userid,
+		TotpLastStep: step,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Two-factor authentication enabled"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func loginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	type challengeRequest struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		Expiresin      int    `json:"expires_in_seconds"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	challengerequest := challengeRequest{}
+	err := decoder.Decode(&challengerequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	userid, err := apiCfg.jwtkeys.ValidateChallengeToken(challengerequest.ChallengeToken)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil || !user.TotpEnabled {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Codes are short enough to guess, so they are throttled like passwords.
+	twofactorkey := twoFactorThrottleKey(user.ID)
+	retryafter, err := apiCfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{twofactorkey, ipThrottleKey(r)})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if retryafter > 0 {
+		writeTooManyAttempts(w, retryafter)
+		return
+	}
+
+	ok, err := checkSecondFactor(r.Context(), user, challengerequest.Code)
+	if err == nil && ok {
+		err = apiCfg.dbQueries.ClearLoginFailures(r.Context(), []string{twofactorkey})
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if !ok {
+		recordLoginFailure(r, user.ID, "wrong second factor", map[string]throttle.Policy{
+			twofactorkey:     accountLoginPolicy,
+			ipThrottleKey(r): ipLoginPolicy,
+		})
+		errdres := errorResponse{Error: "Invalid code"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	writeLoginResponse(w, r, user, accessTokenTTL(challengerequest.Expiresin))
+}
+
+// checkSecondFactor accepts a current TOTP code or an unused recovery code.
+// Either is spent by a successful check.
+func checkSecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	if step, ok := totp.Validate(user.TotpSecret.String, code, time.Now()); ok {
+		used, err := apiCfg.dbQueries.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			ID:           user.ID,
+			TotpLastStep: step,
+		})
+		return used == 1, err
+	}
+
+	code = auth.NormalizeRecoveryCode(code)
+	if len(code) != 11 {
+		return false, nil
+	}
+	recoverycodes, err := apiCfg.dbQueries.ListUnusedRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, recoverycode := range recoverycodes {
+		if auth.CheckPasswordHash(code, recoverycode.CodeHash) == nil {
+			used, err := apiCfg.dbQueries.UseRecoveryCode(ctx, recoverycode.ID)
+			return used == 1, err
+		}
+	}
+	return false, nil
+}
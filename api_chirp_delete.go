@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
 	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
 
 	"github.com/google/uuid"
 )
@@ -19,20 +24,11 @@ func deleteChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
 		Message string `json:"message"`
 	}
 
-	jwttoken, err := auth.GetBearerToken(r.Header)
+	userid, err := authenticate(r, auth.ScopeChirpsWrite)
 	if err != nil {
 		errdres := errorResponse{Error: "Not Authorized"}
 		errson, _ := json.Marshal(errdres)
-		w.WriteHeader(401)
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(errson)
-		return
-	}
-	userid, err := auth.ValidateJWT(jwttoken, apiCfg.jwtscecret)
-	if err != nil {
-		errdres := errorResponse{Error: "Not Authorized"}
-		errson, _ := json.Marshal(errdres)
-		w.WriteHeader(401)
+		w.WriteHeader(authStatus(err))
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(errson)
 		return
@@ -50,7 +46,7 @@ func deleteChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
-	if err != nil {
+	if err != nil || chirp.TombstonedAt.Valid {
 		errdres := errorResponse{Error: "Chirp not found"}
 		errson, _ := json.Marshal(errdres)
 		w.WriteHeader(404)
@@ -70,7 +66,14 @@ func deleteChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = apiCfg.dbQueries.DeleteChirpByID(r.Context(), chirpuuid)
+	// The chirp stays restorable until the purger removes it for good. Undoing
+	// a plain rechirp has nothing to restore, and a leftover row would keep
+	// the user from rechirping again.
+	if chirp.RechirpOf.Valid {
+		err = removeChirp(r.Context(), chirp)
+	} else {
+		err = apiCfg.dbQueries.SoftDeleteChirp(r.Context(), chirp.ID)
+	}
 	if err != nil {
 		errdres := errorResponse{Error: "Database error"}
 		errson, _ := json.Marshal(errdres)
@@ -88,3 +91,80 @@ func deleteChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resjson)
 }
+
+// removeChirp deletes chirp permanently. Chirps with replies become tombstones
+// instead so the thread below them survives. Either way its media goes.
+func removeChirp(ctx context.Context, chirp database.Chirp) error {
+	uploads, err := apiCfg.dbQueries.DeleteChirpMedia(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	err = removeChirpRows(ctx, chirp)
+	if err != nil {
+		return err
+	}
+	deleteMediaFiles(ctx, uploads)
+	if chirp.InReplyTo.Valid {
+		return removeEmptyTombstone(ctx, chirp.InReplyTo.UUID)
+	}
+	return nil
+}
+
+// removeEmptyTombstone removes the chirp parentid if it is a tombstone with
+// no replies left under it, since it only held its place for them. Removing
+// it checks its own parent in turn.
+func removeEmptyTombstone(ctx context.Context, parentid uuid.UUID) error {
+	parent, err := apiCfg.dbQueries.GetChirpByID(ctx, parentid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !parent.TombstonedAt.Valid {
+		return nil
+	}
+	hasreplies, err := apiCfg.dbQueries.ChirpHasReplies(ctx, uuid.NullUUID{UUID: parent.ID, Valid: true})
+	if err != nil || hasreplies {
+		return err
+	}
+	return removeChirp(ctx, parent)
+}
+
+func removeChirpRows(ctx context.Context, chirp database.Chirp) error {
+	hasreplies, err := apiCfg.dbQueries.ChirpHasReplies(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	if !hasreplies {
+		return apiCfg.dbQueries.DeleteChirpByID(ctx, chirp.ID)
+	}
+
+	err = apiCfg.dbQueries.TombstoneChirp(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	err = apiCfg.dbQueries.DeleteChirpTags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	err = apiCfg.dbQueries.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	// Plain rechirps of a tombstone have nothing left to show.
+	return apiCfg.dbQueries.DeleteRechirpsOf(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+}
+
+// deleteMediaFiles removes the stored files of uploads whose rows are
+// already gone, so a file that fails to delete is only logged.
+func deleteMediaFiles(ctx context.Context, uploads []database.MediaUpload) {
+	for _, upload := range uploads {
+		for _, key := range []string{upload.StorageKey, upload.ThumbnailKey} {
+			err := apiCfg.media.Delete(ctx, key)
+			if err != nil {
+				log.Printf("Error deleting media %s of upload %s: %v", key, upload.ID, err)
+			}
+		}
+	}
+}
@@ -1,39 +1,33 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
-	"sort"
 
+	"github.com/felixcao99/chirpy/internal/auth"
 	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/pagination"
 
 	"github.com/google/uuid"
 )
 
 func allChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	type chirpResponse struct {
-		Id        string `json:"id"`
-		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
-		Chirp     string `json:"body"`
-		UserID    string `json:"user_id"`
+	type listResponse struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
 	}
 
 	type errorResponse struct {
 		Error string `json:"error"`
 	}
 
-	// chirps, err := apiCfg.dbQueries.AllChirps(r.Context())
-	// if err != nil {
-	// 	errdres := errorResponse{Error: "Database error"}
-	// 	errson, _ := json.Marshal(errdres)
-	// 	w.WriteHeader(500)
-	// 	w.Header().Set("Content-Type", "application/json")
-	// 	w.Write(errson)
-	// 	return
-	// }
+	viewerid, _ := authenticate(r, auth.ScopeChirpsRead)
+
+	var authorID uuid.NullUUID
+	var cursorCreatedAt sql.NullTime
+	var cursorID uuid.NullUUID
 	var chirps []database.Chirp
-	var err error
 
 	userid := r.URL.Query().Get("author_id")
 	if len(userid) > 0 {
@@ -46,44 +40,83 @@ func allChirpsHandler(w http.ResponseWriter, r *http.Request) {
 			w.Write(errson)
 			return
 		}
-		chirps, err = apiCfg.dbQueries.AllChirpsByUserID(r.Context(), useruuid)
-		if err != nil {
-			errdres := errorResponse{Error: "Database error"}
-			errson, _ := json.Marshal(errdres)
-			w.WriteHeader(500)
-			w.Header().Set("Content-Type", "application/json")
-			w.Write(errson)
-			return
-		}
-	} else {
-		chirps, err = apiCfg.dbQueries.AllChirps(r.Context())
+		authorID = uuid.NullUUID{UUID: useruuid, Valid: true}
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	cursorparam := r.URL.Query().Get("cursor")
+	if len(cursorparam) > 0 {
+		cursor, err := pagination.DecodeCursor(cursorparam)
 		if err != nil {
-			errdres := errorResponse{Error: "Database error"}
+			errdres := errorResponse{Error: "Invalid cursor"}
 			errson, _ := json.Marshal(errdres)
-			w.WriteHeader(500)
+			w.WriteHeader(400)
 			w.Header().Set("Content-Type", "application/json")
 			w.Write(errson)
 			return
 		}
+		cursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
 	}
 
+	// Fetch one extra row so we know whether another page exists.
 	sortby := r.URL.Query().Get("sort")
-	if sortby == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+	switch sortby {
+	case "", "asc":
+		chirps, err = apiCfg.dbQueries.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit + 1,
+		})
+	case "desc":
+		chirps, err = apiCfg.dbQueries.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit + 1,
 		})
+	default:
+		errdres := errorResponse{Error: "Invalid sort"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
 	}
 
-	var res []chirpResponse
-	for _, chirp := range chirps {
-		chirpres := chirpResponse{
-			Id:        chirp.ID.String(),
-			CreatedAt: chirp.CreatedAt.String(),
-			UpdatedAt: chirp.UpdatedAt.String(),
-			Chirp:     chirp.Body,
-			UserID:    chirp.UserID.String(),
-		}
-		res = append(res, chirpres)
+	var res listResponse
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		res.NextCursor = pagination.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	res.Chirps, err = chirpResponses(r.Context(), chirps, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
 	}
 
 	resjson, _ := json.Marshal(res)
@@ -93,18 +126,156 @@ func allChirpsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func getChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
-	type chirpResponse struct {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	viewerid, _ := authenticate(r, auth.ScopeChirpsRead)
+
+	chirpID := r.PathValue("chirpID")
+	chirpuuid, err := uuid.Parse(chirpID)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid chirp ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
+	if err != nil || chirp.TombstonedAt.Valid {
+		errdres := errorResponse{Error: "Chirp not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	visible, err := canSeeHidden(r.Context(), chirp, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if !visible {
+		errdres := errorResponse{Error: "Chirp hidden by moderators"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(451)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirpres, err := chirpResponseFor(r.Context(), chirp, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(chirpres)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func getChirpThreadHandler(w http.ResponseWriter, r *http.Request) {
+	type threadResponse struct {
+		Ancestors []chirpResponse `json:"ancestors"`
+		Chirp     chirpResponse   `json:"chirp"`
+		Replies   []chirpResponse `json:"replies"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	viewerid, _ := authenticate(r, auth.ScopeChirpsRead)
+
+	chirpID := r.PathValue("chirpID")
+	chirpuuid, err := uuid.Parse(chirpID)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid chirp ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
+	if err != nil {
+		errdres := errorResponse{Error: "Chirp not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	ancestors, err := apiCfg.dbQueries.GetChirpAncestors(r.Context(), chirpuuid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	replies, err := apiCfg.dbQueries.GetChirpReplies(r.Context(), uuid.NullUUID{UUID: chirpuuid, Valid: true})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Ancestors, the chirp itself and its replies share one count lookup.
+	thread := append(append(ancestors, chirp), replies...)
+	threadres, err := chirpResponses(r.Context(), thread, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := threadResponse{
+		Ancestors: threadres[:len(ancestors)],
+		Chirp:     threadres[len(ancestors)],
+		Replies:   threadres[len(ancestors)+1:],
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func getChirpRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	type revisionResponse struct {
 		Id        string `json:"id"`
 		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
 		Chirp     string `json:"body"`
-		UserID    string `json:"user_id"`
 	}
 
 	type errorResponse struct {
 		Error string `json:"error"`
 	}
 
+	viewerid, _ := authenticate(r, auth.ScopeChirpsRead)
+
 	chirpID := r.PathValue("chirpID")
 	chirpuuid, err := uuid.Parse(chirpID)
 	if err != nil {
@@ -117,7 +288,7 @@ func getChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
-	if err != nil {
+	if err != nil || chirp.TombstonedAt.Valid {
 		errdres := errorResponse{Error: "Chirp not found"}
 		errson, _ := json.Marshal(errdres)
 		w.WriteHeader(404)
@@ -125,16 +296,46 @@ func getChirpByIDHandler(w http.ResponseWriter, r *http.Request) {
 		w.Write(errson)
 		return
 	}
+	// Earlier bodies are as hidden as the current one.
+	visible, err := canSeeHidden(r.Context(), chirp, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if !visible {
+		errdres := errorResponse{Error: "Chirp hidden by moderators"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(451)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
 
-	chirpres := chirpResponse{
-		Id:        chirp.ID.String(),
-		CreatedAt: chirp.CreatedAt.String(),
-		UpdatedAt: chirp.UpdatedAt.String(),
-		Chirp:     chirp.Body,
-		UserID:    chirp.UserID.String(),
+	revisions, err := apiCfg.dbQueries.ListChirpRevisions(r.Context(), chirpuuid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
 	}
 
-	resjson, _ := json.Marshal(chirpres)
+	// Each revision is the body as it read before the edit that replaced it.
+	res := []revisionResponse{}
+	for _, revision := range revisions {
+		res = append(res, revisionResponse{
+			Id:        revision.ID.String(),
+			CreatedAt: revision.CreatedAt.String(),
+			Chirp:     revision.Body,
+		})
+	}
+
+	resjson, _ := json.Marshal(res)
 	w.WriteHeader(200)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(resjson)
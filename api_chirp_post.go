@@ -1,50 +1,39 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"regexp"
+	"slices"
 
 	"github.com/felixcao99/chirpy/internal/auth"
 	"github.com/felixcao99/chirpy/internal/database"
-	// "github.com/google/uuid"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func postChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	type chirpRequest struct {
-		Chirp string `json:"body"`
+		Chirp     string   `json:"body"`
+		InReplyTo string   `json:"in_reply_to"`
+		RechirpOf string   `json:"rechirp_of"`
+		QuoteOf   string   `json:"quote_of"`
+		MediaIDs  []string `json:"media_ids"`
 		// UserID string `json:"user_id"`
 	}
 
 	type errorResponse struct {
 		Error string `json:"error"`
 	}
-	type chirpResponse struct {
-		Id        string `json:"id"`
-		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
-		Chirp     string `json:"body"`
-		UserID    string `json:"user_id"`
-	}
 
-	var replaced string
-	filter := []string{"kerfuffle", "sharbert", "fornax"}
 	var createPara database.CreateChirpParams
 
-	jwttoken, err := auth.GetBearerToken(r.Header)
+	userid, err := authenticate(r, auth.ScopeChirpsWrite)
 	if err != nil {
 		errdres := errorResponse{Error: "Not Authorized"}
 		errson, _ := json.Marshal(errdres)
-		w.WriteHeader(401)
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(errson)
-		return
-	}
-	userid, err := auth.ValidateJWT(jwttoken, apiCfg.jwtscecret)
-	if err != nil {
-		errdres := errorResponse{Error: "Not Authorized"}
-		errson, _ := json.Marshal(errdres)
-		w.WriteHeader(401)
+		w.WriteHeader(authStatus(err))
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(errson)
 		return
@@ -55,15 +44,158 @@ func postChirpsHandler(w http.ResponseWriter, r *http.Request) {
 	err = decoder.Decode(&chirpbody)
 	if err == nil {
 		if len(chirpbody.Chirp) <= 140 {
-			replaced = chirpbody.Chirp
-			for _, badword := range filter {
-				re := regexp.MustCompile("(?i)" + badword)
-				replaced = re.ReplaceAllString(replaced, "****")
+			replaced, rejected := apiCfg.chirpfilter.Load().Apply(chirpbody.Chirp)
+			if rejected {
+				errdres := errorResponse{Error: "Chirp contains prohibited content"}
+				errson, _ := json.Marshal(errdres)
+				w.WriteHeader(400)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(errson)
+				return
 			}
 			createPara.Body = replaced
 			createPara.UserID = userid
+			// Moderators can still see what was written before filtering.
+			if replaced != chirpbody.Chirp {
+				createPara.OriginalBody = sql.NullString{String: chirpbody.Chirp, Valid: true}
+			}
+
+			if len(chirpbody.InReplyTo) > 0 {
+				parentuuid, err := uuid.Parse(chirpbody.InReplyTo)
+				if err != nil {
+					errdres := errorResponse{Error: "Invalid in_reply_to"}
+					errson, _ := json.Marshal(errdres)
+					w.WriteHeader(400)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(errson)
+					return
+				}
+				parent, err := apiCfg.dbQueries.GetChirpByID(r.Context(), parentuuid)
+				if err != nil || parent.TombstonedAt.Valid {
+					errdres := errorResponse{Error: "Parent chirp not found"}
+					errson, _ := json.Marshal(errdres)
+					w.WriteHeader(404)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(errson)
+					return
+				}
+				createPara.InReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+			}
+
+			// A plain rechirp is just a pointer; a quote adds its own body.
+			referenceid := chirpbody.RechirpOf
+			if len(chirpbody.RechirpOf) > 0 && (len(chirpbody.QuoteOf) > 0 || len(chirpbody.Chirp) > 0 || len(chirpbody.InReplyTo) > 0 || len(chirpbody.MediaIDs) > 0) {
+				errdres := errorResponse{Error: "Rechirps cannot have a body, reply, quote or media"}
+				errson, _ := json.Marshal(errdres)
+				w.WriteHeader(400)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(errson)
+				return
+			}
+			if len(chirpbody.QuoteOf) > 0 {
+				referenceid = chirpbody.QuoteOf
+			}
+			if len(referenceid) > 0 {
+				referenceuuid, err := uuid.Parse(referenceid)
+				if err != nil {
+					errdres := errorResponse{Error: "Invalid chirp reference"}
+					errson, _ := json.Marshal(errdres)
+					w.WriteHeader(400)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(errson)
+					return
+				}
+				referenced, err := apiCfg.dbQueries.GetChirpByID(r.Context(), referenceuuid)
+				if err != nil || referenced.TombstonedAt.Valid || referenced.HiddenAt.Valid {
+					errdres := errorResponse{Error: "Referenced chirp not found"}
+					errson, _ := json.Marshal(errdres)
+					w.WriteHeader(404)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(errson)
+					return
+				}
+				// Rechirping or quoting a rechirp points at the original instead.
+				if referenced.RechirpOf.Valid {
+					referenceuuid = referenced.RechirpOf.UUID
+				}
+				if len(chirpbody.RechirpOf) > 0 {
+					createPara.RechirpOf = uuid.NullUUID{UUID: referenceuuid, Valid: true}
+				} else {
+					createPara.QuoteOf = uuid.NullUUID{UUID: referenceuuid, Valid: true}
+				}
+			}
+
+			var mediaids []uuid.UUID
+			for _, mediaid := range chirpbody.MediaIDs {
+				mediauuid, err := uuid.Parse(mediaid)
+				if err != nil || slices.Contains(mediaids, mediauuid) {
+					errdres := errorResponse{Error: "Invalid media_ids"}
+					errson, _ := json.Marshal(errdres)
+					w.WriteHeader(400)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(errson)
+					return
+				}
+				mediaids = append(mediaids, mediauuid)
+			}
+			if len(mediaids) > maxChirpMedia {
+				errdres := errorResponse{Error: "Too many media attachments"}
+				errson, _ := json.Marshal(errdres)
+				w.WriteHeader(400)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(errson)
+				return
+			}
+			// Only the uploader's own, not yet attached media can be linked.
+			if len(mediaids) > 0 {
+				attachable, err := apiCfg.dbQueries.CountAttachableMedia(r.Context(), database.CountAttachableMediaParams{
+					UserID: userid,
+					Ids:    mediaids,
+				})
+				if err != nil || attachable != int64(len(mediaids)) {
+					errdres := errorResponse{Error: "Media not found"}
+					errson, _ := json.Marshal(errdres)
+					w.WriteHeader(404)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(errson)
+					return
+				}
+			}
 
 			chirp, err := apiCfg.dbQueries.CreateChirp(r.Context(), createPara)
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				errdres := errorResponse{Error: "Chirp already rechirped"}
+				errson, _ := json.Marshal(errdres)
+				w.WriteHeader(409)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(errson)
+				return
+			}
+			if err != nil {
+				errdres := errorResponse{Error: "Database error"}
+				errson, _ := json.Marshal(errdres)
+				w.WriteHeader(500)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(errson)
+				return
+			}
+			if len(mediaids) > 0 {
+				_, err = apiCfg.dbQueries.AttachMediaToChirp(r.Context(), database.AttachMediaToChirpParams{
+					ChirpID: chirp.ID,
+					Ids:     mediaids,
+					UserID:  userid,
+				})
+				if err != nil {
+					errdres := errorResponse{Error: "Database error"}
+					errson, _ := json.Marshal(errdres)
+					w.WriteHeader(500)
+					w.Header().Set("Content-Type", "application/json")
+					w.Write(errson)
+					return
+				}
+			}
+			err = storeChirpEntities(r.Context(), chirp)
 			if err != nil {
 				errdres := errorResponse{Error: "Database error"}
 				errson, _ := json.Marshal(errdres)
@@ -72,12 +204,14 @@ func postChirpsHandler(w http.ResponseWriter, r *http.Request) {
 				w.Write(errson)
 				return
 			}
-			chirpres := chirpResponse{
-				Id:        chirp.ID.String(),
-				CreatedAt: chirp.CreatedAt.String(),
-				UpdatedAt: chirp.UpdatedAt.String(),
-				Chirp:     chirp.Body,
-				UserID:    chirp.UserID.String(),
+			chirpres, err := chirpResponseFor(r.Context(), chirp, userid)
+			if err != nil {
+				errdres := errorResponse{Error: "Database error"}
+				errson, _ := json.Marshal(errdres)
+				w.WriteHeader(500)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(errson)
+				return
 			}
 
 			validjson, _ := json.Marshal(chirpres)
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func editChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type chirpRequest struct {
+		Chirp string `json:"body"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	var editPara database.EditChirpParams
+
+	userid, err := authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirpID := r.PathValue("chirpID")
+	chirpuuid, err := uuid.Parse(chirpID)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid chirp ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
+	if err != nil || chirp.TombstonedAt.Valid {
+		errdres := errorResponse{Error: "Chirp not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	if chirp.UserID != userid {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(403)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	if chirp.RechirpOf.Valid {
+		errdres := errorResponse{Error: "Rechirps cannot be edited"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	chirpbody := chirpRequest{}
+	err = decoder.Decode(&chirpbody)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	if len(chirpbody.Chirp) > 140 {
+		errdres := errorResponse{Error: "Chirp is too long"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	replaced, rejected := apiCfg.chirpfilter.Load().Apply(chirpbody.Chirp)
+	if rejected {
+		errdres := errorResponse{Error: "Chirp contains prohibited content"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Saving the same text again would only add an empty revision.
+	if replaced != chirp.Body {
+		editPara.ID = chirpuuid
+		editPara.Body = replaced
+		if replaced != chirpbody.Chirp {
+			editPara.OriginalBody = sql.NullString{String: chirpbody.Chirp, Valid: true}
+		}
+		chirp, err = apiCfg.dbQueries.EditChirp(r.Context(), editPara)
+		if err != nil {
+			errdres := errorResponse{Error: "Database error"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		err = storeChirpEntities(r.Context(), chirp)
+		if err != nil {
+			errdres := errorResponse{Error: "Database error"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+
+	chirpres, err := chirpResponseFor(r.Context(), chirp, userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(chirpres)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// restoreChirpHandler brings back a chirp its author deleted, along with the
+// plain rechirps that went with it, as long as the restore window is open.
+func restoreChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	userid, err := authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirpuuid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid chirp ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Someone else's deleted chirp is reported as missing, not forbidden, so
+	// deleted chirps cannot be probed for.
+	chirp, err := apiCfg.dbQueries.GetDeletedChirpByID(r.Context(), chirpuuid)
+	if err != nil || chirp.UserID != userid {
+		errdres := errorResponse{Error: "Chirp not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	// A rechirp is restored with its original, not on its own.
+	if chirp.RechirpOf.Valid {
+		errdres := errorResponse{Error: "Rechirps cannot be restored"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	restored, err := apiCfg.dbQueries.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:            chirp.ID,
+		WindowSeconds: int32(apiCfg.restorewindow / time.Second),
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if restored == 0 {
+		errdres := errorResponse{Error: "Restore window has passed"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(410)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirp.DeletedAt.Valid = false
+	chirpres, err := chirpResponseFor(r.Context(), chirp, userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(chirpres)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/pagination"
+	"github.com/felixcao99/chirpy/internal/search"
+
+	"github.com/google/uuid"
+)
+
+func searchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	type listResponse struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	viewerid, _ := authenticate(r, auth.ScopeChirpsRead)
+
+	var authorID uuid.NullUUID
+	var offset int32
+
+	tsquery, err := search.BuildQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		errdres := errorResponse{Error: "Missing search query"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	userid := r.URL.Query().Get("author_id")
+	if len(userid) > 0 {
+		useruuid, err := uuid.Parse(userid)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid user ID"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: useruuid, Valid: true}
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	cursorparam := r.URL.Query().Get("cursor")
+	if len(cursorparam) > 0 {
+		offset, err = pagination.DecodeOffset(cursorparam)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid cursor"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+
+	results, err := apiCfg.dbQueries.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:    tsquery,
+		AuthorID: authorID,
+		Limit:    limit + 1,
+		Offset:   offset,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	var res listResponse
+	if len(results) > int(limit) {
+		results = results[:limit]
+		res.NextCursor = pagination.EncodeOffset(offset + limit)
+	}
+
+	// Results stay in rank order; the rank itself is not part of the chirp shape.
+	var chirps []database.Chirp
+	for _, result := range results {
+		chirps = append(chirps, result.Chirp)
+	}
+	res.Chirps, err = chirpResponses(r.Context(), chirps, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
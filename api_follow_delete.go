@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	userid, err := authenticate(r, auth.ScopeUsersWrite)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	followeeuuid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid user ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	err = apiCfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userid,
+		FolloweeID: followeeuuid,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	unfollowres := successResponse{
+		Message: "User unfollowed",
+	}
+	resjson, _ := json.Marshal(unfollowres)
+	w.WriteHeader(204)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+func followersHandler(w http.ResponseWriter, r *http.Request) {
+	followListHandler(w, r, true)
+}
+
+func followingHandler(w http.ResponseWriter, r *http.Request) {
+	followListHandler(w, r, false)
+}
+
+func followListHandler(w http.ResponseWriter, r *http.Request, followers bool) {
+	type followResponse struct {
+		UserID     string `json:"user_id"`
+		FollowedAt string `json:"followed_at"`
+	}
+
+	type listResponse struct {
+		Users      []followResponse `json:"users"`
+		NextCursor string           `json:"next_cursor,omitempty"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	var cursorCreatedAt sql.NullTime
+	var cursorID uuid.NullUUID
+	var follows []database.ListFollowersRow
+
+	useruuid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid user ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	cursorparam := r.URL.Query().Get("cursor")
+	if len(cursorparam) > 0 {
+		cursor, err := pagination.DecodeCursor(cursorparam)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid cursor"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		cursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	if followers {
+		follows, err = apiCfg.dbQueries.ListFollowers(r.Context(), database.ListFollowersParams{
+			UserID:          useruuid,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit + 1,
+		})
+	} else {
+		var following []database.ListFollowingRow
+		following, err = apiCfg.dbQueries.ListFollowing(r.Context(), database.ListFollowingParams{
+			UserID:          useruuid,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit + 1,
+		})
+		for _, follow := range following {
+			follows = append(follows, database.ListFollowersRow(follow))
+		}
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := listResponse{Users: []followResponse{}}
+	if len(follows) > int(limit) {
+		follows = follows[:limit]
+		last := follows[len(follows)-1]
+		res.NextCursor = pagination.EncodeCursor(last.CreatedAt, last.UserID)
+	}
+
+	for _, follow := range follows {
+		res.Users = append(res.Users, followResponse{
+			UserID:     follow.UserID.String(),
+			FollowedAt: follow.CreatedAt.String(),
+		})
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func followUserHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	userid, err := authenticate(r, auth.ScopeUsersWrite)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	followeeuuid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid user ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	if followeeuuid == userid {
+		errdres := errorResponse{Error: "Cannot follow yourself"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	_, err = apiCfg.dbQueries.GetUserByID(r.Context(), followeeuuid)
+	if err != nil {
+		errdres := errorResponse{Error: "User not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	err = apiCfg.dbQueries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userid,
+		FolloweeID: followeeuuid,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	followres := successResponse{
+		Message: "User followed",
+	}
+	resjson, _ := json.Marshal(followres)
+	w.WriteHeader(204)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func jwksHandler(w http.ResponseWriter, r *http.Request) {
+	resjson, _ := json.Marshal(apiCfg.jwtkeys.JWKS())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(200)
+	w.Write(resjson)
+}
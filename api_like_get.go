@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+func userLikesHandler(w http.ResponseWriter, r *http.Request) {
+	type listResponse struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	viewerid, _ := authenticate(r, auth.ScopeChirpsRead)
+
+	var cursorCreatedAt sql.NullTime
+	var cursorID uuid.NullUUID
+
+	useruuid, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid user ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	cursorparam := r.URL.Query().Get("cursor")
+	if len(cursorparam) > 0 {
+		cursor, err := pagination.DecodeCursor(cursorparam)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid cursor"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		cursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	likes, err := apiCfg.dbQueries.ListLikedChirps(r.Context(), database.ListLikedChirpsParams{
+		UserID:          useruuid,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Pages follow the order the chirps were liked in, newest first.
+	var res listResponse
+	if len(likes) > int(limit) {
+		likes = likes[:limit]
+		last := likes[len(likes)-1]
+		res.NextCursor = pagination.EncodeCursor(last.LikedAt, last.Chirp.ID)
+	}
+
+	var chirps []database.Chirp
+	for _, like := range likes {
+		chirps = append(chirps, like.Chirp)
+	}
+	res.Chirps, err = chirpResponses(r.Context(), chirps, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
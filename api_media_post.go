@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	maxUploadBytes   = 5 << 20
+	mediaQuotaBytes  = 100 << 20
+	maxChirpMedia    = 4
+	thumbnailMaxSize = 320
+)
+
+func uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	userid, err := authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Leave room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+1<<20)
+	file, _, err := r.FormFile("file")
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		errdres := errorResponse{Error: "File is too large"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(413)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Missing file"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid upload"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if len(data) > maxUploadBytes {
+		errdres := errorResponse{Error: "File is too large"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(413)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	contenttype, ext, ok := media.Sniff(data)
+	if !ok {
+		errdres := errorResponse{Error: "Unsupported media type"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(415)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	thumb, width, height, err := media.Thumbnail(data, thumbnailMaxSize)
+	if err != nil {
+		errdres := errorResponse{Error: "Unsupported media type"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(415)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	used, err := apiCfg.dbQueries.GetMediaUsageByUserID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if used+int64(len(data)) > mediaQuotaBytes {
+		errdres := errorResponse{Error: "Media quota exceeded"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(403)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	mediaid := uuid.New()
+	storagekey := mediaid.String() + ext
+	thumbnailkey := mediaid.String() + "_thumb.jpg"
+	err = apiCfg.media.Save(r.Context(), storagekey, bytes.NewReader(data))
+	if err == nil {
+		err = apiCfg.media.Save(r.Context(), thumbnailkey, bytes.NewReader(thumb))
+	}
+	if err != nil {
+		apiCfg.media.Delete(r.Context(), storagekey)
+		errdres := errorResponse{Error: "Storage error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	upload, err := apiCfg.dbQueries.CreateMediaUpload(r.Context(), database.CreateMediaUploadParams{
+		ID:           mediaid,
+		UserID:       userid,
+		ContentType:  contenttype,
+		SizeBytes:    int64(len(data)),
+		StorageKey:   storagekey,
+		ThumbnailKey: thumbnailkey,
+		Width:        int32(width),
+		Height:       int32(height),
+	})
+	if err != nil {
+		apiCfg.media.Delete(r.Context(), storagekey)
+		apiCfg.media.Delete(r.Context(), thumbnailkey)
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(mediaResponseFor(upload))
+	w.WriteHeader(201)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/throttle"
+	"github.com/google/uuid"
+)
+
+func passwordResetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	type resetRequest struct {
+		Email string `json:"email"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	resetrequest := resetRequest{}
+	err := decoder.Decode(&resetrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// The limit is keyed on the address as given, so it applies the same way
+	// whether or not the account exists.
+	resetkey := emailSendThrottleKey(purposePasswordReset, resetrequest.Email)
+	retryafter, err := apiCfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{resetkey, ipEmailSendThrottleKey(r)})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if retryafter > 0 {
+		writeTooManyRequests(w, retryafter, "Too many email requests")
+		return
+	}
+	recordAttempt(r, map[string]throttle.Policy{
+		resetkey:                  emailSendPolicy,
+		ipEmailSendThrottleKey(r): ipEmailSendPolicy,
+	})
+
+	// The response is the same whether or not the account exists, so this
+	// endpoint cannot be used to find out who is registered.
+	user, err := apiCfg.dbQueries.GetUserByEmail(r.Context(), resetrequest.Email)
+	if err == nil {
+		err = apiCfg.dbQueries.ExpireEmailTokens(r.Context(), database.ExpireEmailTokensParams{
+			UserID:  user.ID,
+			Purpose: purposePasswordReset,
+		})
+		if err == nil {
+			err = sendEmailToken(r.Context(), user, purposePasswordReset)
+		}
+		if err != nil {
+			log.Printf("Error starting password reset for %s: %v", user.ID, err)
+		}
+		recordSecurityEvent(r.Context(), r, user.ID, "password_reset_requested", "")
+	}
+
+	res := successResponse{Message: "If the account exists, a reset email has been sent"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(202)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func passwordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	type confirmRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	confirmrequest := confirmRequest{}
+	err := decoder.Decode(&confirmrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	err = auth.ValidatePassword(confirmrequest.Password, "")
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid password: " + err.Error()}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	hashedpassword, err := auth.HashPassword(confirmrequest.Password)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid password"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	emailtoken, err := apiCfg.dbQueries.UseEmailToken(r.Context(), database.UseEmailTokenParams{
+		TokenHash: auth.HashToken(confirmrequest.Token),
+		Purpose:   purposePasswordReset,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		errdres := errorResponse{Error: "Invalid or expired token"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// A link sent to an address the account no longer uses is void.
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), emailtoken.UserID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.Email != emailtoken.Email) {
+		errdres := errorResponse{Error: "Invalid or expired token"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	err = changePassword(r, emailtoken.UserID, hashedpassword)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Password has been reset"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+// changePassword stores a new password hash and logs the user out of every
+// session, since whoever held the old password may hold one of them.
+func changePassword(r *http.Request, userid uuid.UUID, hashedpassword string) error {
+	err := apiCfg.dbQueries.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             userid,
+		HashedPassword: hashedpassword,
+	})
+	if err != nil {
+		return err
+	}
+	return revokeSessionsForPasswordChange(r, userid)
+}
+
+func revokeSessionsForPasswordChange(r *http.Request, userid uuid.UUID) error {
+	err := apiCfg.dbQueries.RevokeUserRefreshTokens(r.Context(), userid)
+	if err != nil {
+		return err
+	}
+	err = apiCfg.dbQueries.RevokeUserAPITokens(r.Context(), userid)
+	if err != nil {
+		return err
+	}
+	recordSecurityEvent(r.Context(), r, userid, "password_changed", "all sessions and API tokens revoked")
+	return nil
+}
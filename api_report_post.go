@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const maxReportDetailsLength = 500
+
+func reportChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type reportRequest struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	type reportResponse struct {
+		Id        string `json:"id"`
+		CreatedAt string `json:"created_at"`
+		ChirpID   string `json:"chirp_id"`
+		Reason    string `json:"reason"`
+		Details   string `json:"details"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	userid, err := authenticate(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirpuuid, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid chirp ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reportrequest := reportRequest{}
+	err = decoder.Decode(&reportrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	reportrequest.Details = strings.TrimSpace(reportrequest.Details)
+	if !slices.Contains(reportReasons, reportrequest.Reason) {
+		errdres := errorResponse{Error: "Reason must be one of " + strings.Join(reportReasons, ", ")}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if utf8.RuneCountInString(reportrequest.Details) > maxReportDetailsLength {
+		errdres := errorResponse{Error: "Details must be at most 500 characters"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	chirp, err := apiCfg.dbQueries.GetChirpByID(r.Context(), chirpuuid)
+	if err != nil || chirp.TombstonedAt.Valid || chirp.HiddenAt.Valid {
+		errdres := errorResponse{Error: "Chirp not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if chirp.UserID == userid {
+		errdres := errorResponse{Error: "Cannot report your own chirp"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	report, err := apiCfg.dbQueries.CreateChirpReport(r.Context(), database.CreateChirpReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: userid,
+		Reason:     reportrequest.Reason,
+		Details:    reportrequest.Details,
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		errdres := errorResponse{Error: "Chirp already reported"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := reportResponse{
+		Id:        report.ID.String(),
+		CreatedAt: report.CreatedAt.String(),
+		ChirpID:   report.ChirpID.String(),
+		Reason:    report.Reason,
+		Details:   report.Details,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(201)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
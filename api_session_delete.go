@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+func revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	sessionuuid, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid session ID"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	revoked, err := apiCfg.dbQueries.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		UserID:   userid,
+		FamilyID: sessionuuid,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if revoked == 0 {
+		errdres := errorResponse{Error: "Session not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Session revoked"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(204)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type sessionResponse struct {
+		Id         string `json:"id"`
+		UserAgent  string `json:"user_agent"`
+		IP         string `json:"ip"`
+		SignedInAt string `json:"signed_in_at"`
+		LastUsedAt string `json:"last_used_at"`
+		ExpiresAt  string `json:"expires_at"`
+	}
+	type sessionsResponse struct {
+		Sessions []sessionResponse `json:"sessions"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	sessions, err := apiCfg.dbQueries.ListUserSessions(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// A session is a login and the refresh tokens rotated from it, so it is
+	// identified by its family rather than by a token value.
+	res := sessionsResponse{Sessions: []sessionResponse{}}
+	for _, session := range sessions {
+		res.Sessions = append(res.Sessions, sessionResponse{
+			Id:         session.FamilyID.String(),
+			UserAgent:  session.UserAgent,
+			IP:         session.Ip,
+			SignedInAt: session.SignedInAt.String(),
+			LastUsedAt: session.LastUsedAt.String(),
+			ExpiresAt:  session.ExpiresAt.String(),
+		})
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/extract"
+	"github.com/felixcao99/chirpy/internal/pagination"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+)
+
+func tagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	type listResponse struct {
+		Chirps     []chirpResponse `json:"chirps"`
+		NextCursor string          `json:"next_cursor,omitempty"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	viewerid, _ := authenticate(r, auth.ScopeChirpsRead)
+
+	var cursorCreatedAt sql.NullTime
+	var cursorID uuid.NullUUID
+
+	tag := extract.NormalizeTag(r.PathValue("tag"))
+	if len(tag) == 0 {
+		errdres := errorResponse{Error: "Invalid tag"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	cursorparam := r.URL.Query().Get("cursor")
+	if len(cursorparam) > 0 {
+		cursor, err := pagination.DecodeCursor(cursorparam)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid cursor"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		cursorCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		cursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	chirps, err := apiCfg.dbQueries.ListChirpsByTag(r.Context(), database.ListChirpsByTagParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit + 1,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	var res listResponse
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		res.NextCursor = pagination.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	res.Chirps, err = chirpResponses(r.Context(), chirps, viewerid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func trendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	type tagResponse struct {
+		Tag        string `json:"tag"`
+		ChirpCount int64  `json:"chirp_count"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	window := defaultTrendingWindow
+	windowparam := r.URL.Query().Get("window")
+	if len(windowparam) > 0 {
+		parsed, err := time.ParseDuration(windowparam)
+		if err != nil || parsed <= 0 || parsed > maxTrendingWindow {
+			errdres := errorResponse{Error: "Invalid window"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		window = parsed
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid limit"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// The window is measured against the database clock, which set created_at.
+	tags, err := apiCfg.dbQueries.TrendingTags(r.Context(), database.TrendingTagsParams{
+		WindowSeconds: int32(window.Seconds()),
+		Limit:         limit,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := []tagResponse{}
+	for _, tag := range tags {
+		res = append(res, tagResponse{
+			Tag:        tag.Tag,
+			ChirpCount: tag.ChirpCount,
+		})
+	}
+
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+)
+
+type apiTokenResponse struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	Token      string   `json:"token,omitempty"`
+}
+
+func apiTokenResponseFor(apitoken database.ApiToken) apiTokenResponse {
+	res := apiTokenResponse{
+		Id:        apitoken.ID.String(),
+		Name:      apitoken.Name,
+		Scopes:    strings.Fields(apitoken.Scopes),
+		CreatedAt: apitoken.CreatedAt.String(),
+	}
+	if apitoken.ExpiresAt.Valid {
+		res.ExpiresAt = apitoken.ExpiresAt.Time.String()
+	}
+	if apitoken.LastUsedAt.Valid {
+		res.LastUsedAt = apitoken.LastUsedAt.Time.String()
+	}
+	return res
+}
+
+func createAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	type tokenRequest struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	tokenrequest := tokenRequest{}
+	err = decoder.Decode(&tokenrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if len(tokenrequest.Name) == 0 || len(tokenrequest.Name) > 100 || tokenrequest.ExpiresInDays < 0 {
+		errdres := errorResponse{Error: "Invalid name or expiry"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	scopes, err := auth.NormalizeScopes(tokenrequest.Scopes)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid scopes"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	token, err := auth.MakeAPIToken()
+	if err != nil {
+		errdres := errorResponse{Error: "Token not generated"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	var expiresat sql.NullTime
+	if tokenrequest.ExpiresInDays > 0 {
+		expiresat = sql.NullTime{Time: time.Now().AddDate(0, 0, tokenrequest.ExpiresInDays), Valid: true}
+	}
+
+	apitoken, err := apiCfg.dbQueries.CreateAPIToken(r.Context(), database.CreateAPITokenParams{
+		UserID:    userid,
+		Name:      tokenrequest.Name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    scopes,
+		ExpiresAt: expiresat,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// The token is only ever shown here; afterwards only its hash exists.
+	res := apiTokenResponseFor(apitoken)
+	res.Token = token
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(201)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -1,20 +1,25 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/felixcao99/chirpy/internal/auth"
 	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/throttle"
 	"github.com/google/uuid"
-
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func userHandler(w http.ResponseWriter, r *http.Request) {
 	type userRequest struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 
 	type errorResponse struct {
@@ -25,6 +30,7 @@ func userHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt string `json:"created_at"`
 		UpdatedAt string `json:"updated_at"`
 		Email     string `json:"email"`
+		Handle    string `json:"handle"`
 		Red       bool   `json:"is_chirpy_red"`
 	}
 
@@ -39,12 +45,54 @@ func userHandler(w http.ResponseWriter, r *http.Request) {
 		w.Write(errson)
 		return
 	}
+	err = auth.ValidatePassword(userrequest.Password, userrequest.Email)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid password: " + err.Error()}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	hashedPassword, err := auth.HashPassword(userrequest.Password)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid password"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
 	var createPara database.CreateUserParams
 	createPara.Email = userrequest.Email
-	hashedPassword, _ := auth.HashPassword(userrequest.Password)
 	createPara.HashedPassword = hashedPassword
+	createPara.Handle = defaultHandle()
+	if len(userrequest.Handle) > 0 {
+		handle, ok := normalizeHandle(userrequest.Handle)
+		if !ok {
+			errdres := errorResponse{Error: "Invalid handle"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		createPara.Handle = handle
+	}
 
 	user, err := apiCfg.dbQueries.CreateUser(r.Context(), createPara)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		errdres := errorResponse{Error: "Email already in use"}
+		if pqErr.Constraint == "users_handle_key" {
+			errdres.Error = "Handle already taken"
+		}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
 	if err != nil {
 		errdres := errorResponse{Error: "Database error"}
 		errson, _ := json.Marshal(errdres)
@@ -54,6 +102,11 @@ func userHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = sendEmailToken(r.Context(), user, purposeVerifyEmail)
+	if err != nil {
+		log.Printf("Error sending verification email to %s: %v", user.ID, err)
+	}
+
 	var isChirpyRed bool
 	if user.IsChirpyRed.Valid {
 		isChirpyRed = user.IsChirpyRed.Bool
@@ -64,6 +117,7 @@ func userHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: user.CreatedAt.String(),
 		UpdatedAt: user.UpdatedAt.String(),
 		Email:     user.Email,
+		Handle:    user.Handle,
 		Red:       isChirpyRed,
 	}
 
@@ -84,18 +138,11 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		Error string `json:"error"`
 	}
 
-	type loginResponse struct {
-		Id         string `json:"id"`
-		CreatedAt  string `json:"created_at"`
-		UpdatedAt  string `json:"updated_at"`
-		Email      string `json:"email"`
-		Token      string `json:"token"`
-		FreshToken string `json:"refresh_token"`
-		Red        bool   `json:"is_chirpy_red"`
+	type challengeResponse struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
 	}
 
-	var insertfreshtoken database.InsertFreshTokenParams
-
 	decoder := json.NewDecoder(r.Body)
 	loginrequest := loginRequest{}
 	err := decoder.Decode(&loginrequest)
@@ -108,14 +155,28 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// if loginrequest.Expiresin > 0 && loginrequest.Expiresin < 3600 {
-	// 	expireseconds = time.Duration(loginrequest.Expiresin) * time.Second
-	// } else {
-	// 	expireseconds = time.Duration(3600) * time.Second
-	// }
+	accountkey := accountThrottleKey(loginrequest.Email)
+	retryafter, err := apiCfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{accountkey, ipThrottleKey(r)})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if retryafter > 0 {
+		writeTooManyAttempts(w, retryafter)
+		return
+	}
+	throttlepolicies := map[string]throttle.Policy{
+		accountkey:       accountLoginPolicy,
+		ipThrottleKey(r): ipLoginPolicy,
+	}
 
 	user, err := apiCfg.dbQueries.GetUserByEmail(r.Context(), loginrequest.Email)
 	if err != nil {
+		recordLoginFailure(r, uuid.Nil, "unknown email "+loginrequest.Email, throttlepolicies)
 		errdres := errorResponse{Error: "Invalid email or password"}
 		errson, _ := json.Marshal(errdres)
 		w.WriteHeader(401)
@@ -126,6 +187,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	err = auth.CheckPasswordHash(loginrequest.Password, user.HashedPassword)
 	if err != nil {
+		recordLoginFailure(r, user.ID, "wrong password", throttlepolicies)
 		errdres := errorResponse{Error: "Invalid email or password"}
 		errson, _ := json.Marshal(errdres)
 		w.WriteHeader(401)
@@ -134,10 +196,89 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// jwttoken, err := auth.MakeJWT(user.ID, apiCfg.jwtscecret, expireseconds)
+	err = apiCfg.dbQueries.ClearLoginFailures(r.Context(), []string{accountkey})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// With 2FA on, the password only earns a challenge that has to be
+	// exchanged at /api/login/2fa along with a code.
+	if user.TotpEnabled {
+		challenge, err := apiCfg.jwtkeys.MakeChallengeToken(user.ID, twoFactorChallengeTTL)
+		if err != nil {
+			errdres := errorResponse{Error: "Token not generated"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		res := challengeResponse{TwoFactorRequired: true, ChallengeToken: challenge}
+		resjson, _ := json.Marshal(res)
+		w.WriteHeader(200)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resjson)
+		return
+	}
+
+	writeLoginResponse(w, r, user, accessTokenTTL(loginrequest.Expiresin))
+}
+
+// accessTokenTTL lets clients ask for a shorter-lived token, or a longer one
+// up to the configured maximum.
+func accessTokenTTL(requested int) time.Duration {
+	// Capping before converting keeps a huge request from overflowing into a
+	// negative duration.
+	if requested > int(apiCfg.maxaccessttl/time.Second) {
+		return apiCfg.maxaccessttl
+	}
+	if requested > 0 {
+		return time.Duration(requested) * time.Second
+	}
+	return apiCfg.accessttl
+}
+
+// writeLoginResponse starts a new session for user and responds with its
+// access and refresh tokens.
+func writeLoginResponse(w http.ResponseWriter, r *http.Request, user database.User, expiresin time.Duration) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	type loginResponse struct {
+		Id         string `json:"id"`
+		CreatedAt  string `json:"created_at"`
+		UpdatedAt  string `json:"updated_at"`
+		Email      string `json:"email"`
+		Handle     string `json:"handle"`
+		Token      string `json:"token"`
+		FreshToken string `json:"refresh_token"`
+		Red        bool   `json:"is_chirpy_red"`
+	}
+
+	// Both the password and the 2FA step end here, so this covers either.
+	if user.SuspendedAt.Valid {
+		errdres := errorResponse{Error: "Account suspended"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(403)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	var insertfreshtoken database.InsertFreshTokenParams
+
 	refreshtoken, _ := auth.MakeRefreshToken()
 	insertfreshtoken.Token = refreshtoken
 	insertfreshtoken.UserID = user.ID
+	insertfreshtoken.FamilyID = uuid.New()
+	insertfreshtoken.UserAgent = r.UserAgent()
+	insertfreshtoken.Ip = clientIP(r)
 	insertedfreshtoken, err := apiCfg.dbQueries.InsertFreshToken(r.Context(), insertfreshtoken)
 	if err != nil {
 		errdres := errorResponse{Error: "Fresh token not generated"}
@@ -148,7 +289,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jwttoken, err := auth.MakeJWT(user.ID, apiCfg.jwtscecret)
+	jwttoken, err := apiCfg.jwtkeys.MakeAccessToken(user.ID, expiresin, auth.ScopeAll, user.IsChirpyRed.Bool, user.Role)
 	if err != nil {
 		errdres := errorResponse{Error: "Token not generated"}
 		errson, _ := json.Marshal(errdres)
@@ -168,6 +309,7 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:  user.CreatedAt.String(),
 		UpdatedAt:  user.UpdatedAt.String(),
 		Email:      user.Email,
+		Handle:     user.Handle,
 		Token:      jwttoken,
 		FreshToken: insertedfreshtoken.Token,
 		Red:        isChirpyRed,
@@ -184,7 +326,8 @@ func refreshHandler(w http.ResponseWriter, r *http.Request) {
 		Error string `json:"error"`
 	}
 	type validResponse struct {
-		AccessToken string `json:"token"`
+		AccessToken  string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	freshtoken, err := auth.GetBearerToken(r.Header)
@@ -206,7 +349,52 @@ func refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	validuserid, err := auth.ValidateFreshToken(storedfreshtoken)
+	if errors.Is(err, auth.ErrRefreshTokenReused) {
+		revokeTokenFamily(r, storedfreshtoken)
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), validuserid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if user.SuspendedAt.Valid || user.DeletedAt.Valid {
+		errdres := errorResponse{Error: "Account suspended"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(403)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	newfreshtoken, err := auth.MakeRefreshToken()
 	if err != nil {
+		errdres := errorResponse{Error: "Fresh token not generated"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	// Only one request can retire a token. Losing that race means the same
+	// token was presented twice, which is treated like any other reuse.
+	_, err = apiCfg.dbQueries.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		ReplacedBy: newfreshtoken,
+		Token:      storedfreshtoken.Token,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		revokeTokenFamily(r, storedfreshtoken)
 		errdres := errorResponse{Error: "Not Authorized"}
 		errson, _ := json.Marshal(errdres)
 		w.WriteHeader(401)
@@ -214,7 +402,31 @@ func refreshHandler(w http.ResponseWriter, r *http.Request) {
 		w.Write(errson)
 		return
 	}
-	accesstoken, err := auth.MakeJWT(validuserid, apiCfg.jwtscecret)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	insertedfreshtoken, err := apiCfg.dbQueries.InsertFreshToken(r.Context(), database.InsertFreshTokenParams{
+		Token:     newfreshtoken,
+		UserID:    validuserid,
+		FamilyID:  storedfreshtoken.FamilyID,
+		UserAgent: r.UserAgent(),
+		Ip:        clientIP(r),
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	accesstoken, err := apiCfg.jwtkeys.MakeAccessToken(validuserid, apiCfg.accessttl, auth.ScopeAll, user.IsChirpyRed.Bool, user.Role)
 	if err != nil {
 		errdres := errorResponse{Error: "Access Token not generated"}
 		errson, _ := json.Marshal(errdres)
@@ -224,7 +436,8 @@ func refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	res := validResponse{
-		AccessToken: accesstoken,
+		AccessToken:  accesstoken,
+		RefreshToken: insertedfreshtoken.Token,
 	}
 	resjson, _ := json.Marshal(res)
 	w.WriteHeader(200)
@@ -232,6 +445,16 @@ func refreshHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(resjson)
 }
 
+// revokeTokenFamily logs out every session descended from the same login as
+// a refresh token that was presented after it had already been rotated.
+func revokeTokenFamily(r *http.Request, refreshtoken database.Refreshtoken) {
+	err := apiCfg.dbQueries.RevokeRefreshTokenFamily(r.Context(), refreshtoken.FamilyID)
+	if err != nil {
+		log.Printf("Error revoking refresh token family %s: %v", refreshtoken.FamilyID, err)
+	}
+	recordSecurityEvent(r.Context(), r, refreshtoken.UserID, "refresh_token_reuse", "family "+refreshtoken.FamilyID.String()+" revoked")
+}
+
 func revokeRefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
 	type errorResponse struct {
 		Error string `json:"error"`
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/throttle"
+)
+
+func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	type deleteRequest struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	deleterequest := deleteRequest{}
+	err = decoder.Decode(&deleterequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Re-checks on a signed-in account are throttled like logins, so a stolen
+	// access token cannot be used to guess the password or code.
+	retryafter, err := apiCfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{accountThrottleKey(user.Email), twoFactorThrottleKey(user.ID), ipThrottleKey(r)})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if retryafter > 0 {
+		writeTooManyAttempts(w, retryafter)
+		return
+	}
+
+	err = auth.CheckPasswordHash(deleterequest.Password, user.HashedPassword)
+	if err != nil {
+		recordLoginFailure(r, user.ID, "wrong password", map[string]throttle.Policy{
+			accountThrottleKey(user.Email): accountLoginPolicy,
+			ipThrottleKey(r):               ipLoginPolicy,
+		})
+		errdres := errorResponse{Error: "Invalid password or code"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(401)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if user.TotpEnabled {
+		ok, err := checkSecondFactor(r.Context(), user, deleterequest.Code)
+		if err != nil {
+			errdres := errorResponse{Error: "Database error"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		if !ok {
+			recordLoginFailure(r, user.ID, "wrong second factor", map[string]throttle.Policy{
+				twoFactorThrottleKey(user.ID): accountLoginPolicy,
+				ipThrottleKey(r):              ipLoginPolicy,
+			})
+			errdres := errorResponse{Error: "Invalid password or code"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(401)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+
+	err = deleteAccount(r, user)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Account deleted"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(204)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
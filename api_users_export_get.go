@@ -0,0 +1,205 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// exportUserHandler sends everything stored about the caller as a zip of
+// JSON files.
+func exportUserHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type profileExport struct {
+		Id               string `json:"id"`
+		CreatedAt        string `json:"created_at"`
+		UpdatedAt        string `json:"updated_at"`
+		Email            string `json:"email"`
+		EmailVerified    bool   `json:"email_verified"`
+		Handle           string `json:"handle"`
+		DisplayName      string `json:"display_name"`
+		Bio              string `json:"bio"`
+		AvatarURL        string `json:"avatar_url"`
+		Red              bool   `json:"is_chirpy_red"`
+		TwoFactorEnabled bool   `json:"two_factor_enabled"`
+	}
+	type chirpExport struct {
+		Id        string `json:"id"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+		Body      string `json:"body"`
+		InReplyTo string `json:"in_reply_to,omitempty"`
+		RechirpOf string `json:"rechirp_of,omitempty"`
+		QuoteOf   string `json:"quote_of,omitempty"`
+		Edited    bool   `json:"edited"`
+		Deleted   bool   `json:"deleted"`
+	}
+	type mediaExport struct {
+		Id          string `json:"id"`
+		CreatedAt   string `json:"created_at"`
+		ChirpID     string `json:"chirp_id,omitempty"`
+		URL         string `json:"url"`
+		ContentType string `json:"content_type"`
+		SizeBytes   int64  `json:"size_bytes"`
+	}
+	type sessionExport struct {
+		Id         string `json:"id"`
+		UserAgent  string `json:"user_agent"`
+		IP         string `json:"ip"`
+		SignedInAt string `json:"signed_in_at"`
+		LastUsedAt string `json:"last_used_at"`
+		ExpiresAt  string `json:"expires_at"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// Everything is loaded before the archive starts so a database error can
+	// still be reported with a status code.
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	chirps, err := apiCfg.dbQueries.ExportChirpsByUserID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	uploads, err := apiCfg.dbQueries.ListMediaByUserID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	sessions, err := apiCfg.dbQueries.ListUserSessions(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	profile := profileExport{
+		Id:               user.ID.String(),
+		CreatedAt:        user.CreatedAt.String(),
+		UpdatedAt:        user.UpdatedAt.String(),
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt.Valid,
+		Handle:           user.Handle,
+		DisplayName:      user.DisplayName,
+		Bio:              user.Bio,
+		AvatarURL:        user.AvatarUrl,
+		Red:              user.IsChirpyRed.Bool,
+		TwoFactorEnabled: user.TotpEnabled,
+	}
+	chirpexports := []chirpExport{}
+	for _, chirp := range chirps {
+		chirpexport := chirpExport{
+			Id:        chirp.ID.String(),
+			CreatedAt: chirp.CreatedAt.String(),
+			UpdatedAt: chirp.UpdatedAt.String(),
+			Body:      chirp.Body,
+			Edited:    chirp.EditedAt.Valid,
+			Deleted:   chirp.TombstonedAt.Valid || chirp.DeletedAt.Valid,
+		}
+		if chirp.InReplyTo.Valid {
+			chirpexport.InReplyTo = chirp.InReplyTo.UUID.String()
+		}
+		if chirp.RechirpOf.Valid {
+			chirpexport.RechirpOf = chirp.RechirpOf.UUID.String()
+		}
+		if chirp.QuoteOf.Valid {
+			chirpexport.QuoteOf = chirp.QuoteOf.UUID.String()
+		}
+		chirpexports = append(chirpexports, chirpexport)
+	}
+	mediaexports := []mediaExport{}
+	for _, upload := range uploads {
+		mediaexport := mediaExport{
+			Id:          upload.ID.String(),
+			CreatedAt:   upload.CreatedAt.String(),
+			URL:         apiCfg.media.URL(upload.StorageKey),
+			ContentType: upload.ContentType,
+			SizeBytes:   upload.SizeBytes,
+		}
+		if upload.ChirpID.Valid {
+			mediaexport.ChirpID = upload.ChirpID.UUID.String()
+		}
+		mediaexports = append(mediaexports, mediaexport)
+	}
+	sessionexports := []sessionExport{}
+	for _, session := range sessions {
+		sessionexports = append(sessionexports, sessionExport{
+			Id:         session.FamilyID.String(),
+			UserAgent:  session.UserAgent,
+			IP:         session.Ip,
+			SignedInAt: session.SignedInAt.String(),
+			LastUsedAt: session.LastUsedAt.String(),
+			ExpiresAt:  session.ExpiresAt.String(),
+		})
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", profile},
+		{"chirps.json", chirpexports},
+		{"media.json", mediaexports},
+		{"sessions.json", sessionexports},
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export-`+user.ID.String()+`.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(200)
+
+	// Once the body has started the status can no longer change, so a
+	// failure here only truncates the archive.
+	archive := zip.NewWriter(w)
+	now := time.Now()
+	for _, file := range files {
+		entry, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			log.Printf("Error writing export for %s: %v", userid, err)
+			return
+		}
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(file.data)
+		if err != nil {
+			log.Printf("Error writing export for %s: %v", userid, err)
+			return
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		log.Printf("Error writing export for %s: %v", userid, err)
+	}
+	recordSecurityEvent(r.Context(), r, userid, "data_exported", "account data archive downloaded")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+func userProfileHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type profileResponse struct {
+		Id             string `json:"id"`
+		CreatedAt      string `json:"created_at"`
+		Handle         string `json:"handle"`
+		DisplayName    string `json:"display_name"`
+		Bio            string `json:"bio"`
+		AvatarURL      string `json:"avatar_url"`
+		ChirpCount     int64  `json:"chirp_count"`
+		FollowerCount  int64  `json:"follower_count"`
+		FollowingCount int64  `json:"following_count"`
+	}
+
+	handle, ok := normalizeHandle(r.PathValue("handle"))
+	if !ok {
+		errdres := errorResponse{Error: "User not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	profile, err := apiCfg.dbQueries.GetUserProfileByHandle(r.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		errdres := errorResponse{Error: "User not found"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(404)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := profileResponse{
+		Id:             profile.ID.String(),
+		CreatedAt:      profile.CreatedAt.String(),
+		Handle:         profile.Handle,
+		DisplayName:    profile.DisplayName,
+		Bio:            profile.Bio,
+		AvatarURL:      profile.AvatarUrl,
+		ChirpCount:     profile.ChirpCount,
+		FollowerCount:  profile.FollowerCount,
+		FollowingCount: profile.FollowingCount,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/throttle"
+	"github.com/lib/pq"
+)
+
+func userPatchHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type userResponse struct {
+		Id            string `json:"id"`
+		CreatedAt     string `json:"created_at"`
+		UpdatedAt     string `json:"updated_at"`
+		Email         string `json:"email"`
+		Red           bool   `json:"is_chirpy_red"`
+		EmailVerified bool   `json:"email_verified"`
+		Handle        string `json:"handle"`
+		DisplayName   string `json:"display_name"`
+		Bio           string `json:"bio"`
+		AvatarURL     string `json:"avatar_url"`
+	}
+
+	// Omitted fields are left unchanged. Only email and password need the
+	// current password.
+	type patchRequest struct {
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+		Handle          *string `json:"handle"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
+		AvatarURL       *string `json:"avatar_url"`
+	}
+
+	userid, err := authenticate(r, auth.ScopeUsersWrite)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	patchrequest := patchRequest{}
+	err = decoder.Decode(&patchrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	// Profile fields can be edited with an API token, but email and password
+	// are security settings and need a password login.
+	if patchrequest.Email != nil || patchrequest.Password != nil {
+		_, err = authenticateLogin(r)
+		if err != nil {
+			errdres := errorResponse{Error: "Not Authorized"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(authStatus(err))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	params := database.PatchUserParams{ID: userid}
+	if patchrequest.Email != nil {
+		email := strings.TrimSpace(*patchrequest.Email)
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != email {
+			errdres := errorResponse{Error: "Invalid email"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		if email != user.Email {
+			params.Email = sql.NullString{String: email, Valid: true}
+		}
+	}
+	if patchrequest.Password != nil {
+		email := user.Email
+		if params.Email.Valid {
+			email = params.Email.String
+		}
+		err = auth.ValidatePassword(*patchrequest.Password, email)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid password: " + err.Error()}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+
+	if patchrequest.Handle != nil {
+		handle, ok := normalizeHandle(*patchrequest.Handle)
+		if !ok {
+			errdres := errorResponse{Error: "Invalid handle"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		params.Handle = sql.NullString{String: handle, Valid: true}
+	}
+	if patchrequest.DisplayName != nil {
+		displayname := strings.TrimSpace(*patchrequest.DisplayName)
+		err = validateDisplayName(displayname)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid display name: " + err.Error()}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		params.DisplayName = sql.NullString{String: displayname, Valid: true}
+	}
+	if patchrequest.Bio != nil {
+		bio := strings.TrimSpace(*patchrequest.Bio)
+		err = validateBio(bio)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid bio: " + err.Error()}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		params.Bio = sql.NullString{String: bio, Valid: true}
+	}
+	if patchrequest.AvatarURL != nil {
+		avatarurl := strings.TrimSpace(*patchrequest.AvatarURL)
+		err = validateAvatarURL(avatarurl)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid avatar URL: " + err.Error()}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		params.AvatarUrl = sql.NullString{String: avatarurl, Valid: true}
+	}
+
+	// The current password is throttled like a login, so a stolen access
+	// token is not a way to guess it.
+	if params.Email.Valid || patchrequest.Password != nil {
+		retryafter, err := apiCfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{accountThrottleKey(user.Email), ipThrottleKey(r)})
+		if err != nil {
+			errdres := errorResponse{Error: "Database error"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		if retryafter > 0 {
+			writeTooManyAttempts(w, retryafter)
+			return
+		}
+		if auth.CheckPasswordHash(patchrequest.CurrentPassword, user.HashedPassword) != nil {
+			recordLoginFailure(r, user.ID, "wrong current password", map[string]throttle.Policy{
+				accountThrottleKey(user.Email): accountLoginPolicy,
+				ipThrottleKey(r):               ipLoginPolicy,
+			})
+			errdres := errorResponse{Error: "Current password is incorrect"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(403)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+	if patchrequest.Password != nil {
+		hashedpassword, err := auth.HashPassword(*patchrequest.Password)
+		if err != nil {
+			errdres := errorResponse{Error: "Invalid password"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(400)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		params.HashedPassword = sql.NullString{String: hashedpassword, Valid: true}
+	}
+
+	updateduser := user
+	if params.Email.Valid || params.HashedPassword.Valid || params.Handle.Valid || params.DisplayName.Valid || params.Bio.Valid || params.AvatarUrl.Valid {
+		updateduser, err = apiCfg.dbQueries.PatchUser(r.Context(), params)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			errdres := errorResponse{Error: "Email already in use"}
+			if pqErr.Constraint == "users_handle_key" {
+				errdres.Error = "Handle already taken"
+			}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(409)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		if err != nil {
+			errdres := errorResponse{Error: "Database error"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+
+	if params.HashedPassword.Valid {
+		err = revokeSessionsForPasswordChange(r, userid)
+		if err != nil {
+			errdres := errorResponse{Error: "Database error"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(500)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+	}
+	if params.Email.Valid {
+		recordSecurityEvent(r.Context(), r, userid, "email_changed", "from "+user.Email)
+		err = sendEmailToken(r.Context(), updateduser, purposeVerifyEmail)
+		if err != nil {
+			log.Printf("Error sending verification email to %s: %v", updateduser.ID, err)
+		}
+	}
+
+	res := userResponse{
+		Id:            updateduser.ID.String(),
+		CreatedAt:     updateduser.CreatedAt.String(),
+		UpdatedAt:     updateduser.UpdatedAt.String(),
+		Email:         updateduser.Email,
+		Red:           updateduser.IsChirpyRed.Bool,
+		EmailVerified: updateduser.EmailVerifiedAt.Valid,
+		Handle:        updateduser.Handle,
+		DisplayName:   updateduser.DisplayName,
+		Bio:           updateduser.Bio,
+		AvatarURL:     updateduser.AvatarUrl,
+	}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/throttle"
+)
+
+func verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	type verifyRequest struct {
+		Token string `json:"token"`
+	}
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	verifyrequest := verifyRequest{}
+	err := decoder.Decode(&verifyrequest)
+	if err != nil {
+		errdres := errorResponse{Error: "Invalid JSON"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	emailtoken, err := apiCfg.dbQueries.UseEmailToken(r.Context(), database.UseEmailTokenParams{
+		TokenHash: auth.HashToken(verifyrequest.Token),
+		Purpose:   purposeVerifyEmail,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		errdres := errorResponse{Error: "Invalid or expired token"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(400)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	// The token proves ownership of the address it was sent to, which may
+	// no longer be the account's email.
+	verified, err := apiCfg.dbQueries.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    emailtoken.UserID,
+		Email: emailtoken.Email,
+	})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if verified == 0 {
+		errdres := errorResponse{Error: "Email address has changed since the token was sent"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Email verified"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(200)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
+
+func resendVerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+	type successResponse struct {
+		Message string `json:"message"`
+	}
+
+	userid, err := authenticateLogin(r)
+	if err != nil {
+		errdres := errorResponse{Error: "Not Authorized"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(authStatus(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	user, err := apiCfg.dbQueries.GetUserByID(r.Context(), userid)
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		errdres := errorResponse{Error: "Email already verified"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(409)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	verifykey := emailSendThrottleKey(purposeVerifyEmail, user.Email)
+	retryafter, err := apiCfg.dbQueries.GetLoginRetryAfter(r.Context(), []string{verifykey, ipEmailSendThrottleKey(r)})
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+	if retryafter > 0 {
+		writeTooManyRequests(w, retryafter, "Too many email requests")
+		return
+	}
+	recordAttempt(r, map[string]throttle.Policy{
+		verifykey:                 emailSendPolicy,
+		ipEmailSendThrottleKey(r): ipEmailSendPolicy,
+	})
+
+	err = apiCfg.dbQueries.ExpireEmailTokens(r.Context(), database.ExpireEmailTokensParams{
+		UserID:  userid,
+		Purpose: purposeVerifyEmail,
+	})
+	if err == nil {
+		err = sendEmailToken(r.Context(), user, purposeVerifyEmail)
+	}
+	if err != nil {
+		errdres := errorResponse{Error: "Database error"}
+		errson, _ := json.Marshal(errdres)
+		w.WriteHeader(500)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(errson)
+		return
+	}
+
+	res := successResponse{Message: "Verification email sent"}
+	resjson, _ := json.Marshal(res)
+	w.WriteHeader(202)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resjson)
+}
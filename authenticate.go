@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+// authenticate resolves the bearer token on r to a user. Access tokens from
+// a login and personal API tokens are both accepted, as long as they carry
+// scope.
+func authenticate(r *http.Request, scope string) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !strings.HasPrefix(token, auth.APITokenPrefix) {
+		claims, err := apiCfg.jwtkeys.ParseAccessToken(token)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if !auth.HasScope(claims.Scope, scope) {
+			return uuid.Nil, auth.ErrInsufficientScope
+		}
+		userid, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		err = checkUserActive(r.Context(), userid)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return userid, nil
+	}
+
+	apitoken, err := apiCfg.dbQueries.GetActiveAPITokenByHash(r.Context(), auth.HashToken(token))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !auth.HasScope(apitoken.Scopes, scope) {
+		return uuid.Nil, auth.ErrInsufficientScope
+	}
+	err = checkUserActive(r.Context(), apitoken.UserID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	apiCfg.dbQueries.TouchAPIToken(r.Context(), apitoken.ID)
+	return apitoken.UserID, nil
+}
+
+// authenticateLogin only accepts access tokens from a password login. It
+// guards account security settings, which an API token must not be able to
+// change.
+func authenticateLogin(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if strings.HasPrefix(token, auth.APITokenPrefix) {
+		return uuid.Nil, auth.ErrInsufficientScope
+	}
+	userid, err := apiCfg.jwtkeys.ValidateJWT(token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = checkUserActive(r.Context(), userid)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return userid, nil
+}
+
+var (
+	errAccountSuspended = errors.New("account suspended")
+	errAccountDeleted   = errors.New("account deleted")
+)
+
+// checkUserActive rejects suspended and deleted accounts, whose access
+// tokens stay valid until they expire.
+func checkUserActive(ctx context.Context, userid uuid.UUID) error {
+	_, err := activeUserRole(ctx, userid)
+	return err
+}
+
+// activeUserRole is checkUserActive that also returns the user's current
+// role, read in the same query.
+func activeUserRole(ctx context.Context, userid uuid.UUID) (string, error) {
+	status, err := apiCfg.dbQueries.GetUserStatus(ctx, userid)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && status.DeletedAt.Valid) {
+		return "", errAccountDeleted
+	}
+	if err != nil {
+		return "", err
+	}
+	if status.SuspendedAt.Valid {
+		return "", errAccountSuspended
+	}
+	return status.Role, nil
+}
+
+// authStatus is the status code for an authenticate error: a valid token
+// without the needed scope, or of a suspended account, is forbidden rather
+// than unauthorized.
+func authStatus(err error) int {
+	if errors.Is(err, auth.ErrInsufficientScope) || errors.Is(err, errAccountSuspended) {
+		return 403
+	}
+	return 401
+}
+
+type roleUserKey struct{}
+
+// middlewareRequireRole only lets a request through when the access token's
+// user has at least role. The role is read from the database rather than the
+// token, so a demotion takes effect on the next request. API tokens are never
+// enough.
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		type errorResponse struct {
+			Error string `json:"error"`
+		}
+
+		token, err := auth.GetBearerToken(r.Header)
+		if err == nil && strings.HasPrefix(token, auth.APITokenPrefix) {
+			err = auth.ErrInsufficientScope
+		}
+		var claims *auth.Claims
+		if err == nil {
+			claims, err = cfg.jwtkeys.ParseAccessToken(token)
+		}
+		var userid uuid.UUID
+		if err == nil {
+			userid, err = uuid.Parse(claims.Subject)
+		}
+		var userrole string
+		if err == nil {
+			userrole, err = activeUserRole(r.Context(), userid)
+		}
+		if err != nil {
+			errdres := errorResponse{Error: "Not Authorized"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(authStatus(err))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+		if !auth.HasRole(userrole, role) {
+			errdres := errorResponse{Error: "Forbidden"}
+			errson, _ := json.Marshal(errdres)
+			w.WriteHeader(403)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(errson)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), roleUserKey{}, userid)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// roleUserID is the user that middlewareRequireRole let through.
+func roleUserID(r *http.Request) uuid.UUID {
+	userid, _ := r.Context().Value(roleUserKey{}).(uuid.UUID)
+	return userid
+}
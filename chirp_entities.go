@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/extract"
+)
+
+// storeChirpEntities indexes the #tags and @mentions in a chirp's stored
+// body. Mentions that do not resolve to a user are dropped.
+func storeChirpEntities(ctx context.Context, chirp database.Chirp) error {
+	err := apiCfg.dbQueries.DeleteChirpTags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	err = apiCfg.dbQueries.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	tags := extract.Hashtags(chirp.Body)
+	if len(tags) > 0 {
+		err = apiCfg.dbQueries.AddChirpTags(ctx, database.AddChirpTagsParams{
+			ChirpID:   chirp.ID,
+			Tags:      tags,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	mentions := extract.Mentions(chirp.Body)
+	if len(mentions) > 0 {
+		err = apiCfg.dbQueries.AddChirpMentions(ctx, database.AddChirpMentionsParams{
+			ChirpID:  chirp.ID,
+			Mentions: mentions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/felixcao99/chirpy/internal/database"
+)
+
+const (
+	defaultRestoreWindow  = 7 * 24 * time.Hour
+	defaultChirpRetention = 30 * 24 * time.Hour
+	chirpPurgeInterval    = time.Hour
+	chirpPurgeBatchSize   = 100
+)
+
+// purgeDeletedChirps removes soft-deleted chirps for good once they are
+// older than the retention period, checking every interval until ctx ends.
+func purgeDeletedChirps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := purgeChirpsOnce(ctx)
+		if err != nil {
+			log.Printf("Error purging deleted chirps: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted chirps", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeChirpsOnce works through everything past retention in batches. A
+// deleted chirp that still has live replies is tombstoned by removeChirp,
+// which also clears deleted_at, so no batch is fetched twice.
+func purgeChirpsOnce(ctx context.Context) (int, error) {
+	retention := int32(apiCfg.chirpretention / time.Second)
+	purged := 0
+	for {
+		chirps, err := apiCfg.dbQueries.ListChirpsToPurge(ctx, database.ListChirpsToPurgeParams{
+			RetentionSeconds: retention,
+			Limit:            chirpPurgeBatchSize,
+		})
+		if err != nil {
+			return purged, err
+		}
+		for _, chirp := range chirps {
+			err = removeChirp(ctx, chirp)
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(chirps) < chirpPurgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type mentionResponse struct {
+	UserID  string `json:"user_id"`
+	Mention string `json:"mention"`
+}
+
+// authorResponse is the public view of a chirp's author; it never carries
+// the email address.
+type authorResponse struct {
+	Id          string `json:"id"`
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+type mediaResponse struct {
+	Id           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int32  `json:"width"`
+	Height       int32  `json:"height"`
+}
+
+func mediaResponseFor(upload database.MediaUpload) mediaResponse {
+	return mediaResponse{
+		Id:           upload.ID.String(),
+		URL:          apiCfg.media.URL(upload.StorageKey),
+		ThumbnailURL: apiCfg.media.URL(upload.ThumbnailKey),
+		ContentType:  upload.ContentType,
+		Width:        upload.Width,
+		Height:       upload.Height,
+	}
+}
+
+type chirpResponse struct {
+	Id           string            `json:"id"`
+	CreatedAt    string            `json:"created_at"`
+	UpdatedAt    string            `json:"updated_at"`
+	Chirp        string            `json:"body"`
+	UserID       string            `json:"user_id"`
+	Author       *authorResponse   `json:"author"`
+	InReplyTo    string            `json:"in_reply_to,omitempty"`
+	ReplyCount   int64             `json:"reply_count"`
+	LikeCount    int64             `json:"like_count"`
+	LikedByMe    bool              `json:"liked_by_me"`
+	Edited       bool              `json:"edited"`
+	Mentions     []mentionResponse `json:"mentions"`
+	Media        []mediaResponse   `json:"media"`
+	RechirpCount int64             `json:"rechirp_count"`
+	RechirpOf    *chirpResponse    `json:"rechirp_of,omitempty"`
+	QuoteOf      *chirpResponse    `json:"quote_of,omitempty"`
+	Deleted      bool              `json:"deleted,omitempty"`
+	Unavailable  bool              `json:"unavailable,omitempty"`
+	Hidden       bool              `json:"hidden,omitempty"`
+}
+
+// chirpResponses builds the JSON form of chirps, loading per-chirp counts in
+// one query per page rather than one per chirp. viewerid is uuid.Nil for
+// anonymous requests.
+func chirpResponses(ctx context.Context, chirps []database.Chirp, viewerid uuid.UUID) ([]chirpResponse, error) {
+	return buildChirpResponses(ctx, chirps, viewerid, true)
+}
+
+// buildChirpResponses embeds rechirped and quoted chirps only when embed is
+// set, so an embedded chirp never pulls in another level.
+func buildChirpResponses(ctx context.Context, chirps []database.Chirp, viewerid uuid.UUID, embed bool) ([]chirpResponse, error) {
+	res := []chirpResponse{}
+	if len(chirps) == 0 {
+		return res, nil
+	}
+
+	var chirpids []uuid.UUID
+	for _, chirp := range chirps {
+		chirpids = append(chirpids, chirp.ID)
+	}
+
+	replycounts := make(map[uuid.UUID]int64)
+	counts, err := apiCfg.dbQueries.CountRepliesByChirpIDs(ctx, chirpids)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		replycounts[count.InReplyTo.UUID] = count.ReplyCount
+	}
+
+	likecounts := make(map[uuid.UUID]int64)
+	likes, err := apiCfg.dbQueries.CountLikesByChirpIDs(ctx, chirpids)
+	if err != nil {
+		return nil, err
+	}
+	for _, like := range likes {
+		likecounts[like.ChirpID] = like.LikeCount
+	}
+
+	likedbyme := make(map[uuid.UUID]bool)
+	if viewerid != uuid.Nil {
+		liked, err := apiCfg.dbQueries.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+			UserID:   viewerid,
+			ChirpIds: chirpids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, chirpid := range liked {
+			likedbyme[chirpid] = true
+		}
+	}
+
+	var userids []uuid.UUID
+	anyhidden := false
+	for _, chirp := range chirps {
+		userids = append(userids, chirp.UserID)
+		anyhidden = anyhidden || chirp.HiddenAt.Valid
+	}
+	moderator := false
+	if anyhidden {
+		moderator, err = isModerator(ctx, viewerid)
+		if err != nil {
+			return nil, err
+		}
+	}
+	authors := make(map[uuid.UUID]*authorResponse)
+	users, err := apiCfg.dbQueries.ListAuthorsByIDs(ctx, userids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		authors[user.ID] = &authorResponse{
+			Id:          user.ID.String(),
+			Handle:      user.Handle,
+			DisplayName: user.DisplayName,
+			AvatarURL:   user.AvatarUrl,
+		}
+	}
+
+	mentions := make(map[uuid.UUID][]mentionResponse)
+	chirpmentions, err := apiCfg.dbQueries.ListMentionsByChirpIDs(ctx, chirpids)
+	if err != nil {
+		return nil, err
+	}
+	for _, mention := range chirpmentions {
+		mentions[mention.ChirpID] = append(mentions[mention.ChirpID], mentionResponse{
+			UserID:  mention.UserID.String(),
+			Mention: mention.Mention,
+		})
+	}
+
+	attachments := make(map[uuid.UUID][]mediaResponse)
+	uploads, err := apiCfg.dbQueries.ListMediaByChirpIDs(ctx, chirpids)
+	if err != nil {
+		return nil, err
+	}
+	for _, upload := range uploads {
+		attachments[upload.ChirpID.UUID] = append(attachments[upload.ChirpID.UUID], mediaResponseFor(upload))
+	}
+
+	rechirpcounts := make(map[uuid.UUID]int64)
+	rechirps, err := apiCfg.dbQueries.CountRechirpsByChirpIDs(ctx, chirpids)
+	if err != nil {
+		return nil, err
+	}
+	for _, rechirp := range rechirps {
+		rechirpcounts[rechirp.RechirpOf.UUID] = rechirp.RechirpCount
+	}
+
+	embedded := make(map[uuid.UUID]*chirpResponse)
+	if embed {
+		var referencedids []uuid.UUID
+		for _, chirp := range chirps {
+			if chirp.RechirpOf.Valid {
+				referencedids = append(referencedids, chirp.RechirpOf.UUID)
+			}
+			if chirp.QuoteOf.Valid {
+				referencedids = append(referencedids, chirp.QuoteOf.UUID)
+			}
+		}
+		if len(referencedids) > 0 {
+			referenced, err := apiCfg.dbQueries.GetChirpsByIDs(ctx, referencedids)
+			if err != nil {
+				return nil, err
+			}
+			referencedres, err := buildChirpResponses(ctx, referenced, viewerid, false)
+			if err != nil {
+				return nil, err
+			}
+			for i := range referencedres {
+				if !referenced[i].TombstonedAt.Valid && !referenced[i].DeletedAt.Valid {
+					embedded[referenced[i].ID] = &referencedres[i]
+				}
+			}
+		}
+	}
+
+	for _, chirp := range chirps {
+		chirpres := chirpResponse{
+			Id:           chirp.ID.String(),
+			CreatedAt:    chirp.CreatedAt.String(),
+			UpdatedAt:    chirp.UpdatedAt.String(),
+			Chirp:        chirp.Body,
+			UserID:       chirp.UserID.String(),
+			Author:       authors[chirp.UserID],
+			ReplyCount:   replycounts[chirp.ID],
+			LikeCount:    likecounts[chirp.ID],
+			LikedByMe:    likedbyme[chirp.ID],
+			Edited:       chirp.EditedAt.Valid,
+			Mentions:     mentions[chirp.ID],
+			Media:        attachments[chirp.ID],
+			RechirpCount: rechirpcounts[chirp.ID],
+			Hidden:       chirp.HiddenAt.Valid,
+		}
+		if chirpres.Mentions == nil {
+			chirpres.Mentions = []mentionResponse{}
+		}
+		if chirpres.Media == nil {
+			chirpres.Media = []mediaResponse{}
+		}
+		if chirp.InReplyTo.Valid {
+			chirpres.InReplyTo = chirp.InReplyTo.UUID.String()
+		}
+		if embed && chirp.RechirpOf.Valid {
+			chirpres.RechirpOf = embedded[chirp.RechirpOf.UUID]
+		}
+		// A quote outlives what it quoted; clients show the gap as unavailable.
+		if embed && chirp.QuoteOf.Valid {
+			chirpres.QuoteOf = embedded[chirp.QuoteOf.UUID]
+			if chirpres.QuoteOf == nil {
+				chirpres.QuoteOf = &chirpResponse{
+					Id:          chirp.QuoteOf.UUID.String(),
+					Mentions:    []mentionResponse{},
+					Media:       []mediaResponse{},
+					Unavailable: true,
+				}
+			}
+		}
+		// A chirp hidden by moderators reads like a tombstone to everyone but
+		// its author and moderators.
+		hidden := chirp.HiddenAt.Valid && chirp.UserID != viewerid && !moderator
+		// A tombstone keeps its place in the thread but hides what was said and by whom.
+		// Soft-deleted chirps only reach here as ancestors or replies, and
+		// read the same way until they are restored or purged.
+		deleted := chirp.TombstonedAt.Valid || chirp.DeletedAt.Valid
+		if deleted || hidden {
+			chirpres.Chirp = ""
+			chirpres.UserID = ""
+			chirpres.Author = nil
+			chirpres.Edited = false
+			chirpres.Mentions = []mentionResponse{}
+			chirpres.Media = []mediaResponse{}
+			chirpres.QuoteOf = nil
+			chirpres.Deleted = deleted
+		}
+		res = append(res, chirpres)
+	}
+	return res, nil
+}
+
+func chirpResponseFor(ctx context.Context, chirp database.Chirp, viewerid uuid.UUID) (chirpResponse, error) {
+	res, err := chirpResponses(ctx, []database.Chirp{chirp}, viewerid)
+	if err != nil {
+		return chirpResponse{}, err
+	}
+	return res[0], nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/felixcao99/chirpy/internal/database"
+)
+
+// runCommand runs a maintenance command instead of the server, e.g.
+// `chirpy promote-admin alice@example.com`.
+func runCommand(ctx context.Context, queries *database.Queries, args []string) error {
+	switch args[0] {
+	case "promote-admin":
+		if len(args) != 2 {
+			return errors.New("usage: chirpy promote-admin <email>")
+		}
+		return promoteFirstAdmin(ctx, queries, args[1])
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// promoteFirstAdmin bootstraps the first admin. Once there is one, further
+// roles are handed out through PUT /admin/users/{userID}/role.
+func promoteFirstAdmin(ctx context.Context, queries *database.Queries, email string) error {
+	promoted, err := queries.PromoteFirstAdmin(ctx, email)
+	if err != nil {
+		return err
+	}
+	if promoted == 0 {
+		return errors.New("no user with that email, or an admin already exists")
+	}
+	fmt.Println("Promoted", email, "to admin")
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/mail"
+)
+
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposePasswordReset = "password_reset"
+
+	verifyEmailTTL   = 48 * time.Hour
+	passwordResetTTL = time.Hour
+)
+
+// sendEmailToken mails user a single-use token for purpose. The mail goes
+// out in the background so response times do not reveal whether an account
+// exists.
+func sendEmailToken(ctx context.Context, user database.User, purpose string) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	ttl := verifyEmailTTL
+	if purpose == purposePasswordReset {
+		ttl = passwordResetTTL
+	}
+	err = apiCfg.dbQueries.CreateEmailToken(ctx, database.CreateEmailTokenParams{
+		TokenHash:  auth.HashToken(token),
+		UserID:     user.ID,
+		Purpose:    purpose,
+		Email:      user.Email,
+		TtlSeconds: int32(ttl / time.Second),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := mail.Message{To: user.Email}
+	switch purpose {
+	case purposeVerifyEmail:
+		msg.Subject = "Verify your Chirpy email address"
+		msg.Body = "Confirm this address by opening the link below within 48 hours:\n\n" +
+			apiCfg.baseurl + "/app/verify-email?token=" + url.QueryEscape(token) + "\n"
+	case purposePasswordReset:
+		msg.Subject = "Reset your Chirpy password"
+		msg.Body = "Someone asked to reset the password for this account. If it was you, open the link below within an hour:\n\n" +
+			apiCfg.baseurl + "/app/reset-password?token=" + url.QueryEscape(token) + "\n\n" +
+			"If it was not you, you can ignore this email.\n"
+	}
+
+	go func() {
+		err := apiCfg.mailer.Send(context.Background(), msg)
+		if err != nil {
+			log.Printf("Error sending %s mail to %s: %v", purpose, msg.To, err)
+		}
+	}()
+	return nil
+}
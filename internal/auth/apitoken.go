@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// APITokenPrefix marks personal API tokens so the bearer token parser can
+// tell them from JWTs without a database lookup.
+const APITokenPrefix = "chirpy_"
+
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+func MakeAPIToken() (string, error) {
+	key, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return APITokenPrefix + key, nil
+}
+
+// HashToken is how random bearer secrets such as API tokens and emailed
+// tokens are stored. They are long and random, so a plain SHA-256 is enough
+// and keeps lookups indexable.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeScopes validates requested scopes and returns them in the space
+// separated form used by the scope claim.
+func NormalizeScopes(scopes []string) (string, error) {
+	known := strings.Fields(ScopeAll)
+	var res []string
+	for _, scope := range scopes {
+		if !slices.Contains(known, scope) {
+			return "", errors.New("unknown scope " + scope)
+		}
+		if !slices.Contains(res, scope) {
+			res = append(res, scope)
+		}
+	}
+	if len(res) == 0 {
+		return "", errors.New("no scopes")
+	}
+	slices.Sort(res)
+	return strings.Join(res, " "), nil
+}
+
+func HasScope(scopes, scope string) bool {
+	return slices.Contains(strings.Fields(scopes), scope)
+}
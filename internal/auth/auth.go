@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/felixcao99/chirpy/internal/database"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -24,38 +23,11 @@ func CheckPasswordHash(password, hash string) error {
 }
 
 func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
-	currentUTC := jwt.NewNumericDate(time.Now().UTC())
-	expireTime := jwt.NewNumericDate(time.Now().UTC().Add(time.Duration(3600) * time.Second))
-
-	claims := &jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  currentUTC,
-		ExpiresAt: expireTime,
-		Subject:   userID.String(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte(tokenSecret))
-	return ss, err
+	return NewHMACKeySet(tokenSecret).MakeJWT(userID)
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
-	if err != nil {
-		return uuid.Nil, err
-	}
-
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
-		userID, err := uuid.Parse(claims.Subject)
-		if err != nil {
-			return uuid.Nil, err
-		}
-		return userID, nil
-	} else {
-		return uuid.Nil, err
-	}
+	return NewHMACKeySet(tokenSecret).ValidateJWT(tokenString)
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -83,7 +55,14 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(key), nil
 }
 
+// ErrRefreshTokenReused means a token that was already rotated out has been
+// presented again, so one of its holders is not the real user.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
 func ValidateFreshToken(refreshtoken database.Refreshtoken) (uuid.UUID, error) {
+	if refreshtoken.RevokedAt.Valid && refreshtoken.ReplacedBy.Valid {
+		return uuid.UUID{}, ErrRefreshTokenReused
+	}
 	if refreshtoken.RevokedAt.Valid {
 		return uuid.UUID{}, errors.New("refresh token revoked")
 	}
@@ -1,8 +1,21 @@
 package auth
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -32,3 +45,256 @@ func TestFreshtoken(t *testing.T) {
 		t.Fatalf("Token %s length is incorrect. Got %d, want 32", token, len(token))
 	}
 }
+
+func TestValidateFreshToken(t *testing.T) {
+	userID := uuid.New()
+	token := database.Refreshtoken{
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	validUserID, err := ValidateFreshToken(token)
+	if err != nil || validUserID != userID {
+		t.Fatalf("Failed to validate refresh token. Got %v, %v", validUserID, err)
+	}
+
+	token.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	_, err = ValidateFreshToken(token)
+	if err == nil || errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Revoked token should fail without reuse. Got %v", err)
+	}
+
+	token.ReplacedBy = sql.NullString{String: "next", Valid: true}
+	_, err = ValidateFreshToken(token)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Rotated token should be reported as reused. Got %v", err)
+	}
+
+	token = database.Refreshtoken{UserID: userID, ExpiresAt: time.Now().Add(-time.Hour)}
+	if _, err := ValidateFreshToken(token); err == nil {
+		t.Fatalf("Expired token should fail")
+	}
+}
+
+func writeKey(t *testing.T, dir, name, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), data, 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	writeKey(t, dir, "2026-01-rsa", "PRIVATE KEY", der)
+
+	oldKeys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+	userID := uuid.New()
+	oldToken, err := oldKeys.MakeJWT(userID)
+	if err != nil {
+		t.Fatalf("Failed to create JWT: %v", err)
+	}
+
+	// Rotate: the new Ed25519 key signs, the RSA key is kept for verification only.
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ = x509.MarshalPKCS8PrivateKey(edKey)
+	writeKey(t, dir, "2026-02-ed25519", "PRIVATE KEY", der)
+	os.Remove(filepath.Join(dir, "2026-01-rsa.pem"))
+	der, _ = x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	writeKey(t, dir, "2026-01-rsa", "PUBLIC KEY", der)
+
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("Failed to load rotated keys: %v", err)
+	}
+	newToken, err := keys.MakeJWT(userID)
+	if err != nil {
+		t.Fatalf("Failed to create JWT: %v", err)
+	}
+	for _, token := range []string{oldToken, newToken} {
+		parsedUserID, err := keys.ValidateJWT(token)
+		if err != nil || parsedUserID != userID {
+			t.Fatalf("Failed to validate JWT after rotation. Got %v, %v", parsedUserID, err)
+		}
+	}
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, &jwt.RegisteredClaims{})
+	if parsed.Header["kid"] != "2026-02-ed25519" || parsed.Method.Alg() != "EdDSA" {
+		t.Fatalf("New token header is incorrect. Got %v", parsed.Header)
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kty != "RSA" || jwks.Keys[1].Crv != "Ed25519" {
+		t.Fatalf("JWKS is incorrect. Got %+v", jwks)
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	writeKey(t, dir, "main", "PRIVATE KEY", der)
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("Failed to load keys: %v", err)
+	}
+
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{Subject: uuid.New().String()})
+	forged.Header["kid"] = "main"
+	forgedString, _ := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	if _, err := keys.ValidateJWT(forgedString); err == nil {
+		t.Fatalf("Expected HS256 token signed with the public key to be rejected")
+	}
+
+	if NewHMACKeySet("secret").JWKS().Keys == nil || len(NewHMACKeySet("secret").JWKS().Keys) != 0 {
+		t.Fatalf("Shared secrets must not be published")
+	}
+}
+
+func TestAccessTokenClaims(t *testing.T) {
+	keys := NewHMACKeySet("mysecret")
+	userID := uuid.New()
+	token, err := keys.MakeAccessToken(userID, time.Minute, ScopeChirpsRead, true, RoleModerator)
+	if err != nil {
+		t.Fatalf("Failed to create JWT: %v", err)
+	}
+	claims, err := keys.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("Failed to parse JWT: %v", err)
+	}
+	if claims.Subject != userID.String() || claims.Scope != ScopeChirpsRead || !claims.IsChirpyRed || claims.Role != RoleModerator {
+		t.Fatalf("Claims are incorrect. Got %+v", claims)
+	}
+	if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime != time.Minute {
+		t.Fatalf("Lifetime is incorrect. Got %v, want 1m", lifetime)
+	}
+
+	other := NewHMACKeySet("mysecret")
+	other.Audience = "billing"
+	if _, err := other.ValidateJWT(token); err == nil {
+		t.Fatalf("Expected token for another audience to be rejected")
+	}
+	other = NewHMACKeySet("mysecret")
+	other.Issuer = "someone-else"
+	if _, err := other.ValidateJWT(token); err == nil {
+		t.Fatalf("Expected token from another issuer to be rejected")
+	}
+
+	expired, _ := keys.MakeAccessToken(userID, -time.Minute, ScopeAll, false, RoleUser)
+	if _, err := keys.ValidateJWT(expired); err == nil {
+		t.Fatalf("Expected expired token to be rejected")
+	}
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	token, err := MakeAPIToken()
+	if err != nil {
+		t.Fatalf("Failed to create API token: %v", err)
+	}
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		t.Fatalf("API token %s is missing its prefix", token)
+	}
+	if HashToken(token) == HashToken(token+"x") || len(HashToken(token)) != 64 {
+		t.Fatalf("API token hash is incorrect")
+	}
+
+	scopes, err := NormalizeScopes([]string{ScopeChirpsWrite, ScopeChirpsRead, ScopeChirpsWrite})
+	if err != nil || scopes != "chirps:read chirps:write" {
+		t.Fatalf("Scopes are incorrect. Got %q, %v", scopes, err)
+	}
+	if !HasScope(scopes, ScopeChirpsRead) || HasScope(scopes, ScopeUsersWrite) {
+		t.Fatalf("HasScope is incorrect for %q", scopes)
+	}
+	if _, err := NormalizeScopes([]string{"admin"}); err == nil {
+		t.Fatalf("Expected unknown scope to be rejected")
+	}
+	if _, err := NormalizeScopes(nil); err == nil {
+		t.Fatalf("Expected empty scopes to be rejected")
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	keys := NewHMACKeySet("mysecret")
+	userID := uuid.New()
+	challenge, err := keys.MakeChallengeToken(userID, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create challenge token: %v", err)
+	}
+	parsedUserID, err := keys.ValidateChallengeToken(challenge)
+	if err != nil || parsedUserID != userID {
+		t.Fatalf("Failed to validate challenge token. Got %v, %v", parsedUserID, err)
+	}
+	if _, err := keys.ValidateJWT(challenge); err == nil {
+		t.Fatalf("Challenge token must not be accepted as an access token")
+	}
+	access, _ := keys.MakeJWT(userID)
+	if _, err := keys.ValidateChallengeToken(access); err == nil {
+		t.Fatalf("Access token must not be accepted as a challenge token")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("Failed to make recovery codes: %v", err)
+	}
+	if len(codes) != 10 || len(codes[0]) != 11 || codes[0][5] != '-' {
+		t.Fatalf("Recovery codes are incorrect. Got %v", codes)
+	}
+	if got := NormalizeRecoveryCode(" AB12C 34DEF "); got != "ab12c-34def" {
+		t.Fatalf("Normalized code is incorrect. Got %q", got)
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"", false},
+		{"short", false},
+		{"Password", false},
+		{"walt.white@example.com", false},
+		{"Walt.White", false},
+		{strings.Repeat("a", 73), false},
+		{"correct horse battery", true},
+		{"s3cret-enough", true},
+	}
+	for _, tt := range tests {
+		err := ValidatePassword(tt.password, "walt.white@example.com")
+		if (err == nil) != tt.ok {
+			t.Fatalf("ValidatePassword(%q) is incorrect. Got %v, want ok=%v", tt.password, err, tt.ok)
+		}
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{RoleAdmin, RoleAdmin, true},
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleModerator, RoleAdmin, false},
+		{RoleUser, RoleModerator, false},
+		{"", RoleUser, false},
+		{"superuser", RoleUser, false},
+	}
+	for _, tt := range tests {
+		if got := HasRole(tt.role, tt.required); got != tt.want {
+			t.Fatalf("HasRole(%q, %q) is incorrect. Got %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
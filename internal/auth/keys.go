@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultIssuer         = "chirpy"
+	DefaultAudience       = "chirpy"
+	DefaultAccessTokenTTL = time.Hour
+
+	challengeAudienceSuffix = "/2fa"
+)
+
+// Scopes limit what an access token may be used for. Tokens from a password
+// login carry ScopeAll.
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+	ScopeUsersWrite  = "users:write"
+	ScopeAll         = ScopeChirpsRead + " " + ScopeChirpsWrite + " " + ScopeUsersWrite
+)
+
+// Key is one entry of a KeySet. Keys loaded from a public key file can only
+// verify tokens; they stay around after a rotation until the tokens they
+// signed have expired.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	signer  interface{}
+	public  interface{}
+	private bool
+}
+
+// KeySet signs access tokens with one key and verifies them with any key it
+// holds, picking the key by the token's kid header.
+type KeySet struct {
+	// Issuer and Audience are written into every token and required when
+	// validating one.
+	Issuer   string
+	Audience string
+	signing  *Key
+	keys     map[string]*Key
+}
+
+// Claims are the access token claims. Scope, IsChirpyRed and Role let other
+// services authorize a request without looking the user up.
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope       string `json:"scope,omitempty"`
+	IsChirpyRed bool   `json:"is_chirpy_red"`
+	Role        string `json:"role,omitempty"`
+}
+
+// NewHMACKeySet keeps the original single shared secret setup, where the
+// same HS256 secret signs and verifies.
+func NewHMACKeySet(secret string) *KeySet {
+	key := &Key{
+		Method:  jwt.SigningMethodHS256,
+		signer:  []byte(secret),
+		public:  []byte(secret),
+		private: true,
+	}
+	return &KeySet{
+		Issuer:   DefaultIssuer,
+		Audience: DefaultAudience,
+		signing:  key,
+		keys:     map[string]*Key{"": key},
+	}
+}
+
+// LoadKeySet reads every .pem file in dir, using the file name without its
+// extension as the kid. Private keys (PKCS#8, or PKCS#1 for RSA) can sign;
+// public keys only verify. The signing key is signingKid, or the private key
+// whose name sorts last when it is empty, so date-prefixed file names rotate
+// naturally.
+func LoadKeySet(dir, signingKid string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	ks := &KeySet{
+		Issuer:   DefaultIssuer,
+		Audience: DefaultAudience,
+		keys:     make(map[string]*Key),
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key, err := parseKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		ks.keys[kid] = key
+		if key.private && (signingKid == "" || signingKid == kid) {
+			ks.signing = key
+		}
+	}
+	if ks.signing == nil {
+		return nil, errors.New("no signing key found in " + dir)
+	}
+	return ks, nil
+}
+
+func parseKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, errors.New("unsupported PEM block " + block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.signer, key.public, key.private = jwt.SigningMethodRS256, k, &k.PublicKey, true
+	case *rsa.PublicKey:
+		key.Method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.signer, key.public, key.private = jwt.SigningMethodEdDSA, k, k.Public(), true
+	case ed25519.PublicKey:
+		key.Method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+	if pub, ok := key.public.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+func (ks *KeySet) MakeJWT(userID uuid.UUID) (string, error) {
+	return ks.MakeAccessToken(userID, DefaultAccessTokenTTL, ScopeAll, false, RoleUser)
+}
+
+func (ks *KeySet) MakeAccessToken(userID uuid.UUID, expiresIn time.Duration, scope string, isChirpyRed bool, role string) (string, error) {
+	return ks.sign(ks.Audience, userID, expiresIn, scope, isChirpyRed, role)
+}
+
+// MakeChallengeToken is handed out after a correct password when the account
+// still needs a second factor. Its audience differs from access tokens, so
+// it cannot be used as one.
+func (ks *KeySet) MakeChallengeToken(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return ks.sign(ks.Audience+challengeAudienceSuffix, userID, expiresIn, "", false, "")
+}
+
+func (ks *KeySet) sign(audience string, userID uuid.UUID, expiresIn time.Duration, scope string, isChirpyRed bool, role string) (string, error) {
+	now := time.Now().UTC()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    ks.Issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Scope:       scope,
+		IsChirpyRed: isChirpyRed,
+		Role:        role,
+	}
+
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.signer)
+}
+
+// ParseAccessToken verifies the signature, expiry, issuer and audience of a
+// token and returns its claims.
+func (ks *KeySet) ParseAccessToken(tokenString string) (*Claims, error) {
+	return ks.parse(tokenString, ks.Audience)
+}
+
+func (ks *KeySet) ValidateChallengeToken(tokenString string) (uuid.UUID, error) {
+	claims, err := ks.parse(tokenString, ks.Audience+challengeAudienceSuffix)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+func (ks *KeySet) parse(tokenString, audience string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, ks.keyFunc,
+		jwt.WithIssuer(ks.Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func (ks *KeySet) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	claims, err := ks.ParseAccessToken(tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+// keyFunc only accepts the algorithm the key was loaded for, so a token
+// cannot pick HS256 and use a published public key as its secret.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.public, nil
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes the public half of every asymmetric key. Shared secrets
+// are never included.
+func (ks *KeySet) JWKS() JWKS {
+	var kids []string
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	res := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		res.Keys = append(res.Keys, jwk)
+	}
+	return res
+}
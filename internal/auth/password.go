@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcrypt ignores everything after 72 bytes, so longer passwords would give a
+// false sense of strength.
+const (
+	MinPasswordLength = 8
+	MaxPasswordBytes  = 72
+)
+
+var commonPasswords = []string{
+	"password", "password1", "12345678", "123456789", "1234567890",
+	"qwertyui", "qwerty123", "iloveyou", "letmein1", "chirpy123",
+}
+
+// ValidatePassword applies the password policy: a minimum length, nothing
+// bcrypt would truncate, and not the email address or a well known password.
+func ValidatePassword(password, email string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return errors.New("password must be at least 8 characters")
+	}
+	if len(password) > MaxPasswordBytes {
+		return errors.New("password must be at most 72 bytes")
+	}
+	lower := strings.ToLower(password)
+	if email != "" && (lower == strings.ToLower(email) || lower == strings.ToLower(strings.Split(email, "@")[0])) {
+		return errors.New("password must not be your email address")
+	}
+	if slices.Contains(commonPasswords, lower) {
+		return errors.New("password is too common")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// MakeRecoveryCodes returns n single-use codes of the form xxxxx-xxxxx for
+// getting past 2FA without the authenticator. They are stored like passwords.
+func MakeRecoveryCodes(n int) ([]string, error) {
+	var codes []string
+	for i := 0; i < n; i++ {
+		key := make([]byte, 5)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(key)
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode undoes what users tend to do to a code when typing
+// it in: capitals, spaces and a missing dash.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(code, " ", ""), "-", ""))
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
@@ -0,0 +1,24 @@
+package auth
+
+import "slices"
+
+// Roles are ordered: each one can do everything the roles before it can.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleOrder = []string{RoleUser, RoleModerator, RoleAdmin}
+
+func ValidRole(role string) bool {
+	return slices.Contains(roleOrder, role)
+}
+
+// HasRole reports whether role grants at least required. Unknown roles
+// grant nothing.
+func HasRole(role, required string) bool {
+	have := slices.Index(roleOrder, role)
+	need := slices.Index(roleOrder, required)
+	return have >= 0 && need >= 0 && have >= need
+}
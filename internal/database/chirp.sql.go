@@ -7,12 +7,14 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const allChirps = `-- name: AllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps WHERE deleted_at IS NULL ORDER BY created_at
 `
 
 func (q *Queries) AllChirps(ctx context.Context) ([]Chirp, error) {
@@ -30,6 +32,15 @@ func (q *Queries) AllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -45,7 +56,7 @@ func (q *Queries) AllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const allChirpsByUserID = `-- name: AllChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at
 `
 
 func (q *Queries) AllChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
@@ -63,6 +74,106 @@ func (q *Queries) AllChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+-- Deleted replies count too: they can still be restored under this chirp.
+SELECT EXISTS (SELECT 1 FROM chirps WHERE in_reply_to = $1)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, inReplyTo uuid.NullUUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, inReplyTo)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const countRechirpsByChirpIDs = `-- name: CountRechirpsByChirpIDs :many
+SELECT rechirp_of, COUNT(*) AS rechirp_count
+FROM chirps
+WHERE rechirp_of = ANY($1::uuid[])
+  AND deleted_at IS NULL
+GROUP BY rechirp_of
+`
+
+type CountRechirpsByChirpIDsRow struct {
+	RechirpOf    uuid.NullUUID
+	RechirpCount int64
+}
+
+func (q *Queries) CountRechirpsByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]CountRechirpsByChirpIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRechirpsByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRechirpsByChirpIDsRow
+	for rows.Next() {
+		var i CountRechirpsByChirpIDsRow
+		if err := rows.Scan(
+			&i.RechirpOf,
+			&i.RechirpCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countRepliesByChirpIDs = `-- name: CountRepliesByChirpIDs :many
+SELECT in_reply_to, COUNT(*) AS reply_count
+FROM chirps
+WHERE in_reply_to = ANY($1::uuid[])
+  AND tombstoned_at IS NULL
+  AND deleted_at IS NULL
+GROUP BY in_reply_to
+`
+
+type CountRepliesByChirpIDsRow struct {
+	InReplyTo  uuid.NullUUID
+	ReplyCount int64
+}
+
+func (q *Queries) CountRepliesByChirpIDs(ctx context.Context, chirpIds []uuid.UUID) ([]CountRepliesByChirpIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, countRepliesByChirpIDs, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountRepliesByChirpIDsRow
+	for rows.Next() {
+		var i CountRepliesByChirpIDsRow
+		if err := rows.Scan(
+			&i.InReplyTo,
+			&i.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
@@ -78,24 +189,39 @@ func (q *Queries) AllChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 }
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, original_body, rechirp_of, quote_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body         string
+	UserID       uuid.UUID
+	InReplyTo    uuid.NullUUID
+	OriginalBody sql.NullString
+	RechirpOf    uuid.NullUUID
+	QuoteOf      uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.OriginalBody,
+		arg.RechirpOf,
+		arg.QuoteOf,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -103,6 +229,15 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.SearchVector,
+		&i.OriginalBody,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.HiddenAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -116,8 +251,127 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps WHERE rechirp_of = $1
+`
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, rechirpOf uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirpsOf, rechirpOf)
+	return err
+}
+
+const deleteUntombstonedUserChirps = `-- name: DeleteUntombstonedUserChirps :exec
+-- NOW() is fixed for the transaction, so this keeps exactly the tombstones
+-- TombstoneRepliedUserChirps made in it, and drops rechirps of them.
+DELETE FROM chirps
+WHERE (user_id = $1 AND tombstoned_at IS DISTINCT FROM NOW())
+   OR rechirp_of IN (SELECT id FROM chirps WHERE user_id = $1)
+`
+
+func (q *Queries) DeleteUntombstonedUserChirps(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUntombstonedUserChirps, userID)
+	return err
+}
+
+const exportChirpsByUserID = `-- name: ExportChirpsByUserID :many
+-- Unlike the other reads this includes soft-deleted chirps, which are still
+-- stored until they are purged.
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps WHERE user_id = $1 ORDER BY created_at
+`
+
+func (q *Queries) ExportChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, exportChirpsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+    SELECT c.id, c.in_reply_to, 1
+    FROM chirps c
+    WHERE c.id = (SELECT p.in_reply_to FROM chirps p WHERE p.id = $1)
+    UNION ALL
+    SELECT c.id, c.in_reply_to, a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.in_reply_to
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.tombstoned_at, chirps.search_vector, chirps.original_body, chirps.edited_at, chirps.rechirp_of, chirps.quote_of, chirps.hidden_at, chirps.deleted_at FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+ORDER BY ancestors.depth DESC
+`
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -129,10 +383,444 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.SearchVector,
+		&i.OriginalBody,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.HiddenAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getChirpReplies = `-- name: GetChirpReplies :many
+-- A deleted reply still shows, as a tombstone, while it has replies of its own.
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps
+WHERE in_reply_to = $1
+  AND (deleted_at IS NULL OR EXISTS (SELECT 1 FROM chirps r WHERE r.in_reply_to = chirps.id AND r.deleted_at IS NULL))
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetChirpReplies(ctx context.Context, inReplyTo uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplies, inReplyTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirpByID = `-- name: GetDeletedChirpByID :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetDeletedChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedChirpByID, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.SearchVector,
+		&i.OriginalBody,
+		&i.EditedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.HiddenAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
+const hideChirp = `-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) HideChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, hideChirp, id)
+	return err
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND tombstoned_at IS NULL
+  AND hidden_at IS NULL
+  AND deleted_at IS NULL
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type ListChirpsAscParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+  AND tombstoned_at IS NULL
+  AND hidden_at IS NULL
+  AND deleted_at IS NULL
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type ListChirpsDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsToPurge = `-- name: ListChirpsToPurge :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, tombstoned_at, search_vector, original_body, edited_at, rechirp_of, quote_of, hidden_at, deleted_at FROM chirps
+WHERE deleted_at < NOW() - $1::int * INTERVAL '1 second'
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type ListChirpsToPurgeParams struct {
+	RetentionSeconds int32
+	Limit            int32
+}
+
+func (q *Queries) ListChirpsToPurge(ctx context.Context, arg ListChirpsToPurgeParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsToPurge, arg.RetentionSeconds, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimelineAsc = `-- name: ListTimelineAsc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.tombstoned_at, chirps.search_vector, chirps.original_body, chirps.edited_at, chirps.rechirp_of, chirps.quote_of, chirps.hidden_at, chirps.deleted_at FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = $1
+  AND chirps.tombstoned_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND ($2::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) > ($2::timestamp, $3::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type ListTimelineAscParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListTimelineAsc(ctx context.Context, arg ListTimelineAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimelineAsc,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimelineDesc = `-- name: ListTimelineDesc :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.tombstoned_at, chirps.search_vector, chirps.original_body, chirps.edited_at, chirps.rechirp_of, chirps.quote_of, chirps.hidden_at, chirps.deleted_at FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = $1
+  AND chirps.tombstoned_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND ($2::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type ListTimelineDescParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+}
+
+func (q *Queries) ListTimelineDesc(ctx context.Context, arg ListTimelineDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimelineDesc,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.SearchVector,
+			&i.OriginalBody,
+			&i.EditedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.HiddenAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const resetChirps = `-- name: ResetChirps :exec
 DELETE FROM chirps
 `
@@ -141,3 +829,155 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const restoreChirp = `-- name: RestoreChirp :execrows
+UPDATE chirps
+SET deleted_at = NULL
+FROM chirps AS original
+WHERE original.id = $1
+  AND original.deleted_at > NOW() - $2::int * INTERVAL '1 second'
+  AND (chirps.id = original.id OR chirps.rechirp_of = original.id)
+  AND chirps.deleted_at = original.deleted_at
+`
+
+type RestoreChirpParams struct {
+	ID            uuid.UUID
+	WindowSeconds int32
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreChirp, arg.ID, arg.WindowSeconds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.tombstoned_at, chirps.search_vector, chirps.original_body, chirps.edited_at, chirps.rechirp_of, chirps.quote_of, chirps.hidden_at, chirps.deleted_at, ts_rank(chirps.search_vector, to_tsquery('english', $1)) AS rank
+FROM chirps
+WHERE chirps.search_vector @@ to_tsquery('english', $1)
+  AND chirps.tombstoned_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND ($2::uuid IS NULL OR chirps.user_id = $2::uuid)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $3 OFFSET $4
+`
+
+type SearchChirpsParams struct {
+	Query    string
+	AuthorID uuid.NullUUID
+	Limit    int32
+	Offset   int32
+}
+
+type SearchChirpsRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.SearchVector,
+			&i.Chirp.OriginalBody,
+			&i.Chirp.EditedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.HiddenAt,
+			&i.Chirp.DeletedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+-- Plain rechirps go with the original, and come back with it on restore.
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE (id = $1 OR rechirp_of = $1) AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+    tombstoned_at = NOW(),
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
+
+const tombstoneRepliedUserChirps = `-- name: TombstoneRepliedUserChirps :execrows
+-- When an account is deleted, its chirps that other users replied to, and
+-- its own chirps above those in the thread, stay as tombstones so the
+-- threads hold together.
+WITH RECURSIVE kept AS (
+    SELECT parent.id, parent.in_reply_to FROM chirps AS parent
+    WHERE parent.user_id = $1
+      AND EXISTS (SELECT 1 FROM chirps AS reply WHERE reply.in_reply_to = parent.id AND reply.user_id <> $1)
+    UNION
+    SELECT chirps.id, chirps.in_reply_to FROM chirps
+    JOIN kept ON chirps.id = kept.in_reply_to
+    WHERE chirps.user_id = $1
+),
+tags AS (
+    DELETE FROM chirp_tags WHERE chirp_id IN (SELECT id FROM kept)
+),
+mentions AS (
+    DELETE FROM chirp_mentions WHERE chirp_id IN (SELECT id FROM kept)
+)
+UPDATE chirps
+SET body = '',
+    tombstoned_at = NOW(),
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id IN (SELECT id FROM kept)
+`
+
+func (q *Queries) TombstoneRepliedUserChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, tombstoneRepliedUserChirps, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
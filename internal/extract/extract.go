@@ -0,0 +1,51 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+const maxTagLength = 64
+
+var (
+	hashtagRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+	mentionRe = regexp.MustCompile(`(?:^|[^\w@])@([\w.%+-]+(?:@[\w-]+(?:\.[\w-]+)*\.[A-Za-z]{2,})?)`)
+)
+
+// Hashtags returns the lowercased, de-duplicated #tags in body in the order
+// they first appear.
+func Hashtags(body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range hashtagRe.FindAllStringSubmatch(body, -1) {
+		tag := NormalizeTag(match[1])
+		if len(tag) == 0 || len(tag) > maxTagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Mentions returns the de-duplicated @handles in body, without the leading
+// @. An @email is still matched as a whole so its local part is not taken
+// for a handle, but it is dropped: resolving it would reveal whether the
+// address has an account.
+func Mentions(body string) []string {
+	var mentions []string
+	seen := make(map[string]bool)
+	for _, match := range mentionRe.FindAllStringSubmatch(body, -1) {
+		mention := strings.TrimRight(match[1], ".")
+		if len(mention) == 0 || strings.Contains(mention, "@") || seen[mention] {
+			continue
+		}
+		seen[mention] = true
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
+
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
@@ -0,0 +1,41 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	cases := map[string][]string{
+		"#Go is fun #golang #go":        {"go", "golang"},
+		"no tags here":                  nil,
+		"issue#42 and &#39; are not":    nil,
+		"(#first) #second, #third.":     {"first", "second", "third"},
+		"##double #snake_case":          {"snake_case"},
+		"#Café":                         {"café"},
+		"multi\n#line":                  {"line"},
+		"https://example.com/page#frag": nil,
+	}
+	for input, want := range cases {
+		got := Hashtags(input)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Hashtags for %q are incorrect. Got %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := map[string][]string{
+		"hi @walt@breakingbad.com and @saul": {"saul"},
+		"@saul @saul again":                  {"saul"},
+		"email me at jesse@example.com":      nil,
+		"thanks @mike.":                      {"mike"},
+		"(@gus)":                             {"gus"},
+	}
+	for input, want := range cases {
+		got := Mentions(input)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Mentions for %q are incorrect. Got %v, want %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	ActionReplace = "replace"
+	ActionReject  = "reject"
+)
+
+type Rule struct {
+	Word      string
+	WholeWord bool
+	Action    string
+}
+
+// Matcher holds every rule compiled into two alternations, one per action,
+// so a chirp is scanned at most twice no matter how many words are listed.
+type Matcher struct {
+	replace *regexp.Regexp
+	reject  *regexp.Regexp
+}
+
+func Compile(rules []Rule) (*Matcher, error) {
+	var replace, reject []string
+	for _, rule := range rules {
+		if len(rule.Word) == 0 {
+			continue
+		}
+		pattern := regexp.QuoteMeta(rule.Word)
+		if rule.WholeWord {
+			pattern = `\b` + pattern + `\b`
+		}
+		if rule.Action == ActionReject {
+			reject = append(reject, pattern)
+		} else {
+			replace = append(replace, pattern)
+		}
+	}
+
+	m := &Matcher{}
+	var err error
+	if len(replace) > 0 {
+		m.replace, err = regexp.Compile("(?i)" + strings.Join(replace, "|"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(reject) > 0 {
+		m.reject, err = regexp.Compile("(?i)" + strings.Join(reject, "|"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Apply returns the body with replaceable words masked, or rejected set when
+// the body hits a reject rule. A nil Matcher lets everything through.
+func (m *Matcher) Apply(body string) (cleaned string, rejected bool) {
+	if m == nil {
+		return body, false
+	}
+	if m.reject != nil && m.reject.MatchString(body) {
+		return body, true
+	}
+	if m.replace != nil {
+		body = m.replace.ReplaceAllString(body, "****")
+	}
+	return body, false
+}
@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	m, err := Compile([]Rule{
+		{Word: "kerfuffle", Action: ActionReplace},
+		{Word: "fornax", WholeWord: true, Action: ActionReplace},
+		{Word: "spam.link", Action: ActionReject},
+	})
+	if err != nil {
+		t.Fatalf("Failed to compile rules: %v", err)
+	}
+
+	cases := map[string]string{
+		"What a Kerfuffle!":        "What a ****!",
+		"kerfufflekerfuffle":       "********",
+		"Fornax is here":           "**** is here",
+		"fornaxes are not matched": "fornaxes are not matched",
+		"spamXlink is fine":        "spamXlink is fine",
+	}
+	for input, want := range cases {
+		got, rejected := m.Apply(input)
+		if rejected {
+			t.Fatalf("Unexpected rejection of %q", input)
+		}
+		if got != want {
+			t.Fatalf("Cleaned body for %q is incorrect. Got %q, want %q", input, got, want)
+		}
+	}
+
+	if _, rejected := m.Apply("visit SPAM.LINK now"); !rejected {
+		t.Fatalf("Expected body to be rejected")
+	}
+}
+
+func TestNilMatcher(t *testing.T) {
+	var m *Matcher
+	got, rejected := m.Apply("kerfuffle")
+	if rejected || got != "kerfuffle" {
+		t.Fatalf("Nil matcher changed body. Got %q, rejected %v", got, rejected)
+	}
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	msg := Message{To: "walt@example.com", Subject: "Hello", Body: "line one\nline two"}
+	data, err := format("chirpy@example.com", msg, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to format message: %v", err)
+	}
+	want := "From: chirpy@example.com\r\nTo: walt@example.com\r\nSubject: Hello\r\n" +
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n\r\nline one\r\nline two"
+	if string(data) != want {
+		t.Fatalf("Message is incorrect. Got %q, want %q", data, want)
+	}
+
+	msg.Subject = "Hello\r\nBcc: victim@example.com"
+	if _, err := format("chirpy@example.com", msg, time.Now()); err == nil {
+		t.Fatalf("Expected header injection to be refused")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir, "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+	err = mailer.Send(context.Background(), Message{To: "walt@example.com", Subject: "Hi", Body: "body"})
+	if err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one message file, got %d", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "To: walt@example.com\r\n") || !strings.HasSuffix(string(data), "\r\n\r\nbody") {
+		t.Fatalf("Message file is incorrect. Got %q", data)
+	}
+}
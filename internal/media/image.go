@@ -0,0 +1,109 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+// maxPixels keeps a small but highly compressed upload from decoding into
+// hundreds of megabytes.
+const maxPixels = 4_000_000
+
+// At most this many images are decoded at once, which bounds the memory
+// uploads can take however many arrive together.
+var thumbnailSlots = make(chan struct{}, 4)
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Sniff reports the content type of data from its leading bytes, ignoring
+// whatever the client claimed, and the file extension to store it under.
+func Sniff(data []byte) (contentType, ext string, ok bool) {
+	contentType = http.DetectContentType(data)
+	ext, ok = extensions[contentType]
+	return contentType, ext, ok
+}
+
+// Thumbnail decodes an image and returns it scaled to fit within maxDim on
+// both sides as a JPEG, together with the original dimensions.
+func Thumbnail(data []byte, maxDim int) (thumb []byte, width, height int, err error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, 0, 0, errors.New("image dimensions out of range")
+	}
+	thumbnailSlots <- struct{}{}
+	defer func() { <-thumbnailSlots }()
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	tw, th := fit(cfg.Width, cfg.Height, maxDim)
+	dst := scale(src, tw, th)
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), cfg.Width, cfg.Height, nil
+}
+
+func fit(w, h, maxDim int) (int, int) {
+	if w <= maxDim && h <= maxDim {
+		return w, h
+	}
+	if w >= h {
+		return maxDim, max(1, h*maxDim/w)
+	}
+	return max(1, w*maxDim/h), maxDim
+}
+
+// scale box-filters src down to w x h over a white background, since JPEG
+// has no alpha channel. Source pixels are summed straight into the
+// destination's cells, so no full-size copy of src is made.
+func scale(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	sums := make([][4]uint64, w*h)
+	for sy := 0; sy < sh; sy++ {
+		y := sy * h / sh
+		for sx := 0; sx < sw; sx++ {
+			x := sx * w / sw
+			// RGBA is alpha-premultiplied, so adding the missing coverage
+			// composites the pixel over white.
+			r, g, bl, a := src.At(b.Min.X+sx, b.Min.Y+sy).RGBA()
+			cell := &sums[y*w+x]
+			cell[0] += uint64(r + 0xffff - a)
+			cell[1] += uint64(g + 0xffff - a)
+			cell[2] += uint64(bl + 0xffff - a)
+			cell[3]++
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cell := sums[y*w+x]
+			n := max(cell[3], 1)
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(cell[0] / n >> 8)
+			dst.Pix[i+1] = uint8(cell[1] / n >> 8)
+			dst.Pix[i+2] = uint8(cell[2] / n >> 8)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
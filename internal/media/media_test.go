@@ -0,0 +1,90 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSniff(t *testing.T) {
+	contentType, ext, ok := Sniff(testPNG(t, 2, 2))
+	if !ok || contentType != "image/png" || ext != ".png" {
+		t.Fatalf("Sniff of PNG is incorrect. Got %q %q %v", contentType, ext, ok)
+	}
+	if _, _, ok := Sniff([]byte("<html><body>not an image</body></html>")); ok {
+		t.Fatalf("Expected HTML to be rejected")
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	thumb, width, height, err := Thumbnail(testPNG(t, 640, 320), 100)
+	if err != nil {
+		t.Fatalf("Failed to make thumbnail: %v", err)
+	}
+	if width != 640 || height != 320 {
+		t.Fatalf("Original size is incorrect. Got %dx%d, want 640x320", width, height)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("Thumbnail is not a JPEG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Fatalf("Thumbnail size is incorrect. Got %dx%d, want 100x50", b.Dx(), b.Dy())
+	}
+
+	if _, _, _, err := Thumbnail([]byte("garbage"), 100); err == nil {
+		t.Fatalf("Expected error for invalid image")
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewLocalStorage(dir, "/media/")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := storage.Save(ctx, "abc.png", strings.NewReader("data")); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "abc.png"))
+	if err != nil || string(saved) != "data" {
+		t.Fatalf("Saved file is incorrect. Got %q, %v", saved, err)
+	}
+	if url := storage.URL("abc.png"); url != "/media/abc.png" {
+		t.Fatalf("URL is incorrect. Got %q", url)
+	}
+
+	if err := storage.Save(ctx, "../escape.png", strings.NewReader("x")); err == nil {
+		t.Fatalf("Expected error for key outside storage dir")
+	}
+
+	if err := storage.Delete(ctx, "abc.png"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if err := storage.Delete(ctx, "abc.png"); err != nil {
+		t.Fatalf("Deleting a missing key should succeed: %v", err)
+	}
+}
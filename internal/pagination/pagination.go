@@ -0,0 +1,81 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func EncodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := strconv.FormatInt(createdAt.UnixMicro(), 10) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	micros, idstr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	id, err := uuid.Parse(idstr)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+	return Cursor{CreatedAt: time.UnixMicro(usec).UTC(), ID: id}, nil
+}
+
+// Ranked results have no stable sort key, so their cursor is an offset.
+func EncodeOffset(offset int32) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset|" + strconv.Itoa(int(offset))))
+}
+
+func DecodeOffset(cursor string) (int32, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	n, found := strings.CutPrefix(string(raw), "offset|")
+	if !found {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.ParseInt(n, 10, 32)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return int32(offset), nil
+}
+
+func ParseLimit(limit string) (int32, error) {
+	if len(limit) == 0 {
+		return DefaultLimit, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid limit")
+	}
+	if n > MaxLimit {
+		n = MaxLimit
+	}
+	return int32(n), nil
+}
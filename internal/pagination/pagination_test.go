@@ -0,0 +1,62 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 8, 1, 12, 30, 45, 123456000, time.UTC)
+	id := uuid.New()
+	cursor, err := DecodeCursor(EncodeCursor(createdAt, id))
+	if err != nil {
+		t.Fatalf("Failed to decode cursor: %v", err)
+	}
+	if !cursor.CreatedAt.Equal(createdAt) {
+		t.Fatalf("CreatedAt does not match. Got %v, want %v", cursor.CreatedAt, createdAt)
+	}
+	if cursor.ID != id {
+		t.Fatalf("ID does not match. Got %v, want %v", cursor.ID, id)
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, cursor := range []string{"", "not-base64!", "bm9waXBl", "MTIzfG5vdC1hLXV1aWQ"} {
+		if _, err := DecodeCursor(cursor); err == nil {
+			t.Fatalf("Expected error for cursor %q", cursor)
+		}
+	}
+}
+
+func TestOffsetRoundTrip(t *testing.T) {
+	offset, err := DecodeOffset(EncodeOffset(40))
+	if err != nil {
+		t.Fatalf("Failed to decode offset: %v", err)
+	}
+	if offset != 40 {
+		t.Fatalf("Offset does not match. Got %d, want 40", offset)
+	}
+	if _, err := DecodeOffset(EncodeCursor(time.Now(), uuid.New())); err == nil {
+		t.Fatalf("Expected error decoding a keyset cursor as an offset")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	cases := map[string]int32{"": DefaultLimit, "5": 5, "1000": MaxLimit}
+	for input, want := range cases {
+		got, err := ParseLimit(input)
+		if err != nil {
+			t.Fatalf("Failed to parse limit %q: %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("Limit for %q is incorrect. Got %d, want %d", input, got, want)
+		}
+	}
+	for _, input := range []string{"0", "-1", "abc"} {
+		if _, err := ParseLimit(input); err == nil {
+			t.Fatalf("Expected error for limit %q", input)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// BuildQuery turns a user search string into a to_tsquery expression.
+// "Quoted words" match as a phrase, a trailing * matches by prefix and
+// every remaining term must be present.
+func BuildQuery(q string) (string, error) {
+	var terms []string
+	for i, segment := range strings.Split(q, `"`) {
+		if i%2 == 1 {
+			if words := lexemes(segment); len(words) > 0 {
+				terms = append(terms, strings.Join(words, " <-> "))
+			}
+			continue
+		}
+		for _, token := range strings.Fields(segment) {
+			words := lexemes(token)
+			if len(words) == 0 {
+				continue
+			}
+			if strings.HasSuffix(token, "*") {
+				words[len(words)-1] += ":*"
+			}
+			terms = append(terms, strings.Join(words, " <-> "))
+		}
+	}
+	if len(terms) == 0 {
+		return "", errors.New("empty search query")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// lexemes strips everything tsquery would treat as an operator.
+func lexemes(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
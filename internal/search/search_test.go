@@ -0,0 +1,37 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestBuildQuery(t *testing.T) {
+	cases := map[string]string{
+		"kerfuffle":                 "kerfuffle",
+		"Quick brown":               "quick & brown",
+		`"quick brown" fox`:         "quick <-> brown & fox",
+		"chirp*":                    "chirp:*",
+		`boot "dev chirpy`:          "boot & dev <-> chirpy",
+		"don't & drop | table) !":   "don <-> t & drop & table",
+		`"  " tea*pot`:              "tea <-> pot",
+		"café naïve":                "café & naïve",
+		"a:* <-> b":                 "a:* & b",
+		`"phrase" "another phrase"`: "phrase & another <-> phrase",
+	}
+	for input, want := range cases {
+		got, err := BuildQuery(input)
+		if err != nil {
+			t.Fatalf("Failed to build query for %q: %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("Query for %q is incorrect. Got %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestBuildEmptyQuery(t *testing.T) {
+	for _, input := range []string{"", "   ", `""`, "&|!()"} {
+		if _, err := BuildQuery(input); err == nil {
+			t.Fatalf("Expected error for query %q", input)
+		}
+	}
+}
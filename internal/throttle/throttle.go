@@ -0,0 +1,26 @@
+package throttle
+
+import "time"
+
+// Policy turns a count of recent failures into how long the next attempt
+// has to wait. Nothing happens below Threshold; from there the lockout
+// doubles with every further failure, up to Max.
+type Policy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+func (p Policy) Delay(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	delay := p.Base
+	for i := p.Threshold; i < failures; i++ {
+		delay *= 2
+		if delay >= p.Max {
+			return p.Max
+		}
+	}
+	return min(delay, p.Max)
+}
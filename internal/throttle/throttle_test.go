@@ -0,0 +1,27 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	policy := Policy{Threshold: 5, Base: 30 * time.Second, Max: time.Hour}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, 30 * time.Second},
+		{6, time.Minute},
+		{8, 4 * time.Minute},
+		{12, time.Hour},
+		{1000, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := policy.Delay(tt.failures); got != tt.want {
+			t.Fatalf("Delay after %d failures is incorrect. Got %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
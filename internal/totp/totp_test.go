@@ -0,0 +1,63 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B for the SHA1 key.
+func TestRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got := hotp(key, uint64(Step(time.Unix(tt.unix, 0))), 8)
+		if got != tt.want {
+			t.Fatalf("Code at %d is incorrect. Got %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, now)
+	if err != nil || len(code) != Digits {
+		t.Fatalf("Failed to make code. Got %q, %v", code, err)
+	}
+
+	step, ok := Validate(secret, code, now.Add(Period*time.Second))
+	if !ok || step != Step(now) {
+		t.Fatalf("Code from the previous period should be accepted. Got %d %v", step, ok)
+	}
+	if _, ok := Validate(secret, code, now.Add(3*Period*time.Second)); ok {
+		t.Fatalf("Code from three periods ago should be rejected")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Fatalf("Short code should be rejected")
+	}
+	if _, ok := Validate(strings.ToLower(secret), code, now); !ok {
+		t.Fatalf("Lowercase secret should be accepted")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("chirpy", "walt@example.com", "JBSWY3DPEHPK3PXP")
+	want := "otpauth://totp/chirpy:walt@example.com?algorithm=SHA1&digits=6&issuer=chirpy&period=30&secret=JBSWY3DPEHPK3PXP"
+	if uri != want {
+		t.Fatalf("URI is incorrect. Got %s, want %s", uri, want)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/throttle"
+	"github.com/google/uuid"
+)
+
+// An IP gets more room than an account since many users can share one.
+var (
+	accountLoginPolicy = throttle.Policy{Threshold: 5, Base: 30 * time.Second, Max: time.Hour}
+	ipLoginPolicy      = throttle.Policy{Threshold: 20, Base: 30 * time.Second, Max: time.Hour}
+)
+
+// Emails are limited per address and per IP so the reset and verification
+// endpoints cannot be used to flood an inbox, or to keep voiding the link
+// the owner is about to use.
+var (
+	emailSendPolicy   = throttle.Policy{Threshold: 3, Base: time.Minute, Max: time.Hour}
+	ipEmailSendPolicy = throttle.Policy{Threshold: 20, Base: time.Minute, Max: time.Hour}
+)
+
+// loginFailureWindow is how long failures are remembered after the last one.
+const loginFailureWindow = time.Hour
+
+func accountThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func twoFactorThrottleKey(userid uuid.UUID) string {
+	return "2fa:" + userid.String()
+}
+
+func ipThrottleKey(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+func emailSendThrottleKey(purpose, email string) string {
+	return purpose + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipEmailSendThrottleKey(r *http.Request) string {
+	return "ip-email:" + clientIP(r)
+}
+
+// recordLoginFailure counts a failed attempt against every key, locks the
+// keys whose policy says so, and audits the attempt.
+func recordLoginFailure(r *http.Request, userid uuid.UUID, reason string, policies map[string]throttle.Policy) {
+	recordAttempt(r, policies)
+	recordSecurityEvent(r.Context(), r, userid, "login_failed", reason)
+}
+
+// recordAttempt counts an attempt against every key and locks the keys whose
+// policy says so.
+func recordAttempt(r *http.Request, policies map[string]throttle.Policy) {
+	for key, policy := range policies {
+		failures, err := apiCfg.dbQueries.RecordLoginFailure(r.Context(), database.RecordLoginFailureParams{
+			Key:           key,
+			WindowSeconds: int32(loginFailureWindow / time.Second),
+		})
+		if err != nil {
+			log.Printf("Error recording login failure for %s: %v", key, err)
+			continue
+		}
+		delay := policy.Delay(int(failures))
+		if delay == 0 {
+			continue
+		}
+		err = apiCfg.dbQueries.LockLogin(r.Context(), database.LockLoginParams{
+			Seconds: int32(delay / time.Second),
+			Key:     key,
+		})
+		if err != nil {
+			log.Printf("Error locking %s: %v", key, err)
+		}
+	}
+}
+
+func writeTooManyAttempts(w http.ResponseWriter, retryafter int32) {
+	writeTooManyRequests(w, retryafter, "Too many login attempts")
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryafter int32, message string) {
+	type errorResponse struct {
+		Error string `json:"error"`
+	}
+
+	errdres := errorResponse{Error: message}
+	errson, _ := json.Marshal(errdres)
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryafter)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(429)
+	w.Write(errson)
+}
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	// "encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	// "regexp"
 	"sync/atomic"
+	"time"
 
-	// "github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/auth"
 	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/felixcao99/chirpy/internal/filter"
+	"github.com/felixcao99/chirpy/internal/mail"
+	"github.com/felixcao99/chirpy/internal/media"
 	// "github.com/google/uuid"
 	"github.com/joho/godotenv"
 
@@ -20,10 +26,21 @@ import (
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	db             *sql.DB
 	dbQueries      *database.Queries
 	platform       string
 	jwtscecret     string
+	jwtkeys        *auth.KeySet
+	accessttl      time.Duration
+	maxaccessttl   time.Duration
 	polkakey       string
+	chirpfilter    atomic.Pointer[filter.Matcher]
+	media          media.Storage
+	mailer         mail.Mailer
+	baseurl        string
+	deletionpolicy string
+	restorewindow  time.Duration
+	chirpretention time.Duration
 }
 
 var apiCfg *apiConfig
@@ -34,38 +51,172 @@ func main() {
 	platform := os.Getenv("PLATFORM")
 	jwtscecret := os.Getenv("JWT_SECRET")
 	polkakey := os.Getenv("POLKA_KEY")
+	mediadir := os.Getenv("MEDIA_DIR")
+	if mediadir == "" {
+		mediadir = "media"
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		fmt.Println("Error connecting to the database:", err)
 		return
 	}
 	dbQueries := database.New(db)
+	if len(os.Args) > 1 {
+		err = runCommand(context.Background(), dbQueries, os.Args[1:])
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	apiCfg = &apiConfig{}
+	apiCfg.db = db
 	apiCfg.dbQueries = dbQueries
 	apiCfg.platform = platform
 	apiCfg.jwtscecret = jwtscecret
+	apiCfg.accessttl, err = durationEnv("JWT_ACCESS_TTL", auth.DefaultAccessTokenTTL)
+	if err != nil {
+		fmt.Println("Error reading JWT_ACCESS_TTL:", err)
+		return
+	}
+	apiCfg.maxaccessttl, err = durationEnv("JWT_MAX_ACCESS_TTL", apiCfg.accessttl)
+	if err != nil || apiCfg.maxaccessttl < apiCfg.accessttl {
+		fmt.Println("JWT_MAX_ACCESS_TTL must be a duration no shorter than JWT_ACCESS_TTL")
+		return
+	}
+	// With a key directory tokens are signed with RS256 or EdDSA keys that
+	// other services can fetch from the JWKS endpoint; otherwise the shared
+	// JWT_SECRET is used as before.
+	if keydir := os.Getenv("JWT_KEY_DIR"); keydir != "" {
+		apiCfg.jwtkeys, err = auth.LoadKeySet(keydir, os.Getenv("JWT_SIGNING_KID"))
+		if err != nil {
+			fmt.Println("Error loading JWT keys:", err)
+			return
+		}
+	} else {
+		apiCfg.jwtkeys = auth.NewHMACKeySet(jwtscecret)
+	}
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		apiCfg.jwtkeys.Issuer = issuer
+	}
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		apiCfg.jwtkeys.Audience = audience
+	}
 	apiCfg.polkakey = polkakey
+	apiCfg.media, err = media.NewLocalStorage(mediadir, "/media/")
+	if err != nil {
+		fmt.Println("Error creating media storage:", err)
+		return
+	}
+	apiCfg.baseurl = strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	if apiCfg.baseurl == "" {
+		apiCfg.baseurl = "http://localhost:8080"
+	}
+	apiCfg.deletionpolicy = os.Getenv("ACCOUNT_DELETION_POLICY")
+	if apiCfg.deletionpolicy == "" {
+		apiCfg.deletionpolicy = deletionPolicyDelete
+	}
+	if apiCfg.deletionpolicy != deletionPolicyDelete && apiCfg.deletionpolicy != deletionPolicyAnonymize {
+		fmt.Println("ACCOUNT_DELETION_POLICY must be delete or anonymize")
+		return
+	}
+	apiCfg.restorewindow, err = durationEnv("CHIRP_RESTORE_WINDOW", defaultRestoreWindow)
+	if err != nil {
+		fmt.Println("Error reading CHIRP_RESTORE_WINDOW:", err)
+		return
+	}
+	apiCfg.chirpretention, err = durationEnv("CHIRP_RETENTION", defaultChirpRetention)
+	if err != nil || apiCfg.chirpretention < apiCfg.restorewindow {
+		fmt.Println("CHIRP_RETENTION must be a duration no shorter than CHIRP_RESTORE_WINDOW")
+		return
+	}
+	apiCfg.mailer, err = newMailer(platform)
+	if err != nil {
+		fmt.Println("Error creating mailer:", err)
+		return
+	}
+	// Without the filters every chirp would go through unchecked, so the
+	// server does not start.
+	err = apiCfg.reloadFilters(context.Background())
+	if err != nil {
+		fmt.Println("Error loading chirp filters:", err)
+		return
+	}
+	go purgeDeletedChirps(context.Background(), chirpPurgeInterval)
 
 	serverMux := http.NewServeMux()
 	serverMux.Handle("/assets/", apiCfg.middlewareMetricsInc(http.FileServer(http.Dir("."))))
+	serverMux.Handle("/media/", apiCfg.middlewareMetricsInc(http.StripPrefix("/media/", noDirListing(http.FileServer(http.Dir(mediadir))))))
 	serverMux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app/", http.FileServer(http.Dir(".")))))
 	serverMux.HandleFunc("GET /api/healthz", healthzHandler)
+	serverMux.HandleFunc("GET /.well-known/jwks.json", jwksHandler)
 	// serverMux.HandleFunc("POST /api/validate_chirp", validateChirpHandler)
 	// serverMux.HandleFunc("GET /api/metrics", metricsHandler)
 	serverMux.HandleFunc("POST /api/users", userHandler)
-	serverMux.HandleFunc("GET /admin/metrics", adminMetricsHandler)
+	serverMux.Handle("GET /admin/metrics", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminMetricsHandler)))
 	// serverMux.HandleFunc("POST /api/reset", metricsReset)
-	serverMux.HandleFunc("POST /admin/reset", metricsReset)
+	serverMux.Handle("POST /admin/reset", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(metricsReset)))
+	serverMux.Handle("GET /admin/filters", apiCfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(adminListFiltersHandler)))
+	serverMux.Handle("POST /admin/filters", apiCfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(adminCreateFilterHandler)))
+	serverMux.Handle("PUT /admin/filters/{filterID}", apiCfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(adminUpdateFilterHandler)))
+	serverMux.Handle("DELETE /admin/filters/{filterID}", apiCfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(adminDeleteFilterHandler)))
+	serverMux.Handle("GET /admin/chirps/{chirpID}", apiCfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(adminGetChirpHandler)))
+	serverMux.Handle("GET /admin/reports", apiCfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(adminReportQueueHandler)))
+	serverMux.Handle("POST /admin/reports/{chirpID}", apiCfg.middlewareRequireRole(auth.RoleModerator, http.HandlerFunc(adminModerateChirpHandler)))
+	serverMux.Handle("POST /admin/users/{userID}/unlock", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminUnlockUserHandler)))
+	serverMux.Handle("PUT /admin/users/{userID}/role", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminSetUserRoleHandler)))
+	serverMux.Handle("GET /admin/users", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminListUsersHandler)))
+	serverMux.Handle("GET /admin/users/{userID}", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminGetUserHandler)))
+	serverMux.Handle("POST /admin/users/{userID}/suspend", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminSuspendUserHandler)))
+	serverMux.Handle("POST /admin/users/{userID}/unsuspend", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminUnsuspendUserHandler)))
+	serverMux.Handle("POST /admin/users/{userID}/password-reset", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminForcePasswordResetHandler)))
+	serverMux.Handle("POST /admin/users/{userID}/logout", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminLogoutUserHandler)))
+	serverMux.Handle("PUT /admin/users/{userID}/red", apiCfg.middlewareRequireRole(auth.RoleAdmin, http.HandlerFunc(adminSetUserRedHandler)))
+	serverMux.HandleFunc("POST /api/media", uploadMediaHandler)
 	serverMux.HandleFunc("POST /api/chirps", postChirpsHandler)
+	serverMux.HandleFunc("GET /api/chirps/search", searchChirpsHandler)
 	serverMux.HandleFunc("GET /api/chirps/{chirpID}", getChirpByIDHandler)
+	serverMux.HandleFunc("PUT /api/chirps/{chirpID}", editChirpHandler)
+	serverMux.HandleFunc("GET /api/chirps/{chirpID}/revisions", getChirpRevisionsHandler)
+	serverMux.HandleFunc("GET /api/chirps/{chirpID}/thread", getChirpThreadHandler)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/likes", likeChirpHandler)
+	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", unlikeChirpHandler)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/reports", reportChirpHandler)
 	serverMux.HandleFunc("DELETE /api/chirps/{chirpID}", deleteChirpByIDHandler)
+	serverMux.HandleFunc("POST /api/chirps/{chirpID}/restore", restoreChirpHandler)
 	serverMux.HandleFunc("GET /api/chirps", allChirpsHandler)
 	serverMux.HandleFunc("POST /api/login", loginHandler)
+	serverMux.HandleFunc("POST /api/login/2fa", loginTwoFactorHandler)
 	serverMux.HandleFunc("POST /api/refresh", refreshHandler)
 	serverMux.HandleFunc("POST /api/revoke", revokeRefreshTokenHandler)
+	serverMux.HandleFunc("GET /api/sessions", sessionsHandler)
+	serverMux.HandleFunc("GET /api/tokens", listAPITokensHandler)
+	serverMux.HandleFunc("POST /api/tokens", createAPITokenHandler)
+	serverMux.HandleFunc("DELETE /api/tokens/{tokenID}", revokeAPITokenHandler)
+	serverMux.HandleFunc("DELETE /api/sessions/{sessionID}", revokeSessionHandler)
+	serverMux.HandleFunc("POST /api/sessions/revoke-all", revokeAllSessionsHandler)
 	serverMux.HandleFunc("PUT /api/users", userUpdateHandler)
+	serverMux.HandleFunc("PATCH /api/users", userPatchHandler)
+	serverMux.HandleFunc("DELETE /api/users", deleteUserHandler)
+	serverMux.HandleFunc("GET /api/users/export", exportUserHandler)
+	serverMux.HandleFunc("GET /api/users/{handle}", userProfileHandler)
+	serverMux.HandleFunc("POST /api/users/verify-email", verifyEmailHandler)
+	serverMux.HandleFunc("POST /api/users/verify-email/resend", resendVerifyEmailHandler)
+	serverMux.HandleFunc("POST /api/password-reset/request", passwordResetRequestHandler)
+	serverMux.HandleFunc("POST /api/password-reset/confirm", passwordResetConfirmHandler)
+	serverMux.HandleFunc("POST /api/users/2fa", enrollTwoFactorHandler)
+	serverMux.HandleFunc("POST /api/users/2fa/confirm", confirmTwoFactorHandler)
+	serverMux.HandleFunc("DELETE /api/users/2fa", disableTwoFactorHandler)
 	serverMux.HandleFunc("POST /api/polka/webhooks", polkaWebhooksHandler)
+	serverMux.HandleFunc("POST /api/users/{userID}/follow", followUserHandler)
+	serverMux.HandleFunc("DELETE /api/users/{userID}/follow", unfollowUserHandler)
+	serverMux.HandleFunc("GET /api/users/{userID}/followers", followersHandler)
+	serverMux.HandleFunc("GET /api/users/{userID}/following", followingHandler)
+	serverMux.HandleFunc("GET /api/users/{userID}/likes", userLikesHandler)
+	serverMux.HandleFunc("GET /api/timeline", timelineHandler)
+	serverMux.HandleFunc("GET /api/tags/trending", trendingTagsHandler)
+	serverMux.HandleFunc("GET /api/tags/{tag}/chirps", tagChirpsHandler)
 
 	serverMux.HandleFunc("GET /api/test/{chirpID}", testHandler)
 
@@ -88,6 +239,84 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+// newMailer picks the mailer from MAILER: "smtp" for real delivery, "file"
+// to drop messages into MAIL_DIR, or "log" to print them. Log is only the
+// default in dev, since it writes reset and verification tokens to the logs.
+func newMailer(platform string) (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "chirpy@localhost"
+	}
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return mail.NewSMTPMailer(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return mail.NewFileMailer(dir, from)
+	case "":
+		if platform != "dev" {
+			return nil, fmt.Errorf("MAILER must be set outside dev")
+		}
+		return mail.LogMailer{}, nil
+	case "log":
+		return mail.LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", os.Getenv("MAILER"))
+	}
+}
+
+// durationEnv reads a duration such as "15m" from the environment, falling
+// back to def when the variable is unset.
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("%s must be positive", name)
+	}
+	return d, err
+}
+
+// noDirListing keeps the media directory from being browsable, so only
+// someone who was given an upload's URL can fetch it.
+func noDirListing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reloadFilters recompiles the chirp filter from the filters table. Handlers
+// that change the table call it so new rules apply to the next chirp.
+func (cfg *apiConfig) reloadFilters(ctx context.Context) error {
+	filters, err := cfg.dbQueries.ListFilters(ctx)
+	if err != nil {
+		return err
+	}
+	var rules []filter.Rule
+	for _, f := range filters {
+		rules = append(rules, filter.Rule{
+			Word:      f.Word,
+			WholeWord: f.WholeWord,
+			Action:    f.Action,
+		})
+	}
+	matcher, err := filter.Compile(rules)
+	if err != nil {
+		return err
+	}
+	cfg.chirpfilter.Store(matcher)
+	return nil
+}
+
 // func metricsHandler(w http.ResponseWriter, r *http.Request) {
 // 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 // 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/felixcao99/chirpy/internal/auth"
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Report reasons, matching the check constraint on chirp_reports.
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "misinformation", "other"}
+
+// Moderator actions on a reported chirp, matching moderation_actions.
+const (
+	moderationDismiss       = "dismiss"
+	moderationHide          = "hide"
+	moderationDelete        = "delete"
+	moderationSuspendAuthor = "suspend_author"
+)
+
+// isModerator looks the role up rather than trusting a token, since the
+// optional viewer on public routes may hold an API token. Suspended and
+// deleted accounts are never moderators.
+func isModerator(ctx context.Context, userid uuid.UUID) (bool, error) {
+	if userid == uuid.Nil {
+		return false, nil
+	}
+	role, err := activeUserRole(ctx, userid)
+	if errors.Is(err, errAccountSuspended) || errors.Is(err, errAccountDeleted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return auth.HasRole(role, auth.RoleModerator), nil
+}
+
+// canSeeHidden reports whether viewerid may see chirp although moderators
+// have hidden it: only its author and moderators can.
+func canSeeHidden(ctx context.Context, chirp database.Chirp, viewerid uuid.UUID) (bool, error) {
+	if !chirp.HiddenAt.Valid || chirp.UserID == viewerid {
+		return true, nil
+	}
+	return isModerator(ctx, viewerid)
+}
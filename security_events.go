@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/felixcao99/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// clientIP is the address of the peer that sent the request. Forwarded
+// headers are ignored since any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// recordSecurityEvent logs the event and keeps it in security_events for
+// later review. userid may be uuid.Nil when no account is known.
+func recordSecurityEvent(ctx context.Context, r *http.Request, userid uuid.UUID, event, detail string) {
+	log.Printf("security event %s: user=%s ip=%s %s", event, userid, clientIP(r), detail)
+	err := apiCfg.dbQueries.CreateSecurityEvent(ctx, database.CreateSecurityEventParams{
+		UserID: uuid.NullUUID{UUID: userid, Valid: userid != uuid.Nil},
+		Event:  event,
+		Ip:     clientIP(r),
+		Detail: detail,
+	})
+	if err != nil {
+		log.Printf("Error recording security event %s: %v", event, err)
+	}
+}
@@ -1,25 +1,208 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, original_body, rechirp_of, quote_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
 )
 RETURNING *;
 
 -- name: AllChirps :many
-SELECT * FROM chirps ORDER BY created_at;
+SELECT * FROM chirps WHERE deleted_at IS NULL ORDER BY created_at;
 
 -- name: AllChirpsByUserID :many
+SELECT * FROM chirps WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at;
+
+-- name: ExportChirpsByUserID :many
+-- Unlike the other reads this includes soft-deleted chirps, which are still
+-- stored until they are purged.
 SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at;
 
 -- name: GetChirpByID :one
-SELECT * FROM chirps WHERE id = $1;
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: ResetChirps :exec
 DELETE FROM chirps;
 
 -- name: DeleteChirpByID :exec
-DELETE FROM chirps WHERE id = $1;
\ No newline at end of file
+DELETE FROM chirps WHERE id = $1;
+
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND tombstoned_at IS NULL
+  AND hidden_at IS NULL
+  AND deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND tombstoned_at IS NULL
+  AND hidden_at IS NULL
+  AND deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors (id, in_reply_to, depth) AS (
+    SELECT c.id, c.in_reply_to, 1
+    FROM chirps c
+    WHERE c.id = (SELECT p.in_reply_to FROM chirps p WHERE p.id = $1)
+    UNION ALL
+    SELECT c.id, c.in_reply_to, a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.in_reply_to
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpReplies :many
+-- A deleted reply still shows, as a tombstone, while it has replies of its own.
+SELECT * FROM chirps
+WHERE in_reply_to = $1
+  AND (deleted_at IS NULL OR EXISTS (SELECT 1 FROM chirps r WHERE r.in_reply_to = chirps.id AND r.deleted_at IS NULL))
+ORDER BY created_at, id;
+
+-- name: CountRepliesByChirpIDs :many
+SELECT in_reply_to, COUNT(*) AS reply_count
+FROM chirps
+WHERE in_reply_to = ANY(sqlc.arg('chirp_ids')::uuid[])
+  AND tombstoned_at IS NULL
+  AND deleted_at IS NULL
+GROUP BY in_reply_to;
+
+-- name: ChirpHasReplies :one
+-- Deleted replies count too: they can still be restored under this chirp.
+SELECT EXISTS (SELECT 1 FROM chirps WHERE in_reply_to = $1);
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '',
+    tombstoned_at = NOW(),
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListTimelineAsc :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND chirps.tombstoned_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTimelineDesc :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND chirps.tombstoned_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps), ts_rank(chirps.search_vector, to_tsquery('english', sqlc.arg('query'))) AS rank
+FROM chirps
+WHERE chirps.search_vector @@ to_tsquery('english', sqlc.arg('query'))
+  AND chirps.tombstoned_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: CountRechirpsByChirpIDs :many
+SELECT rechirp_of, COUNT(*) AS rechirp_count
+FROM chirps
+WHERE rechirp_of = ANY(sqlc.arg('chirp_ids')::uuid[])
+  AND deleted_at IS NULL
+GROUP BY rechirp_of;
+
+-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps WHERE rechirp_of = $1;
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = NOW()
+WHERE id = $1;
+
+-- name: SoftDeleteChirp :exec
+-- Plain rechirps go with the original, and come back with it on restore.
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE (id = $1 OR rechirp_of = $1) AND deleted_at IS NULL;
+
+-- name: GetDeletedChirpByID :one
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: RestoreChirp :execrows
+UPDATE chirps
+SET deleted_at = NULL
+FROM chirps AS original
+WHERE original.id = sqlc.arg('id')
+  AND original.deleted_at > NOW() - sqlc.arg('window_seconds')::int * INTERVAL '1 second'
+  AND (chirps.id = original.id OR chirps.rechirp_of = original.id)
+  AND chirps.deleted_at = original.deleted_at;
+
+-- name: ListChirpsToPurge :many
+SELECT * FROM chirps
+WHERE deleted_at < NOW() - sqlc.arg('retention_seconds')::int * INTERVAL '1 second'
+ORDER BY deleted_at
+LIMIT sqlc.arg('limit');
+
+-- name: TombstoneRepliedUserChirps :execrows
+-- When an account is deleted, its chirps that other users replied to, and
+-- its own chirps above those in the thread, stay as tombstones so the
+-- threads hold together.
+WITH RECURSIVE kept AS (
+    SELECT parent.id, parent.in_reply_to FROM chirps AS parent
+    WHERE parent.user_id = sqlc.arg('user_id')
+      AND EXISTS (SELECT 1 FROM chirps AS reply WHERE reply.in_reply_to = parent.id AND reply.user_id <> sqlc.arg('user_id'))
+    UNION
+    SELECT chirps.id, chirps.in_reply_to FROM chirps
+    JOIN kept ON chirps.id = kept.in_reply_to
+    WHERE chirps.user_id = sqlc.arg('user_id')
+),
+tags AS (
+    DELETE FROM chirp_tags WHERE chirp_id IN (SELECT id FROM kept)
+),
+mentions AS (
+    DELETE FROM chirp_mentions WHERE chirp_id IN (SELECT id FROM kept)
+)
+UPDATE chirps
+SET body = '',
+    tombstoned_at = NOW(),
+    deleted_at = NULL,
+    updated_at = NOW()
+WHERE id IN (SELECT id FROM kept);
+
+-- name: DeleteUntombstonedUserChirps :exec
+-- NOW() is fixed for the transaction, so this keeps exactly the tombstones
+-- TombstoneRepliedUserChirps made in it, and drops rechirps of them.
+DELETE FROM chirps
+WHERE (user_id = $1 AND tombstoned_at IS DISTINCT FROM NOW())
+   OR rechirp_of IN (SELECT id FROM chirps WHERE user_id = $1);
@@ -0,0 +1,22 @@
+-- name: CreateEmailToken :exec
+INSERT INTO email_tokens (token_hash, created_at, user_id, purpose, email, expires_at)
+VALUES (
+    sqlc.arg('token_hash'),
+    NOW(),
+    sqlc.arg('user_id'),
+    sqlc.arg('purpose'),
+    sqlc.arg('email'),
+    NOW() + sqlc.arg('ttl_seconds')::int * INTERVAL '1 second'
+);
+
+-- name: UseEmailToken :one
+UPDATE email_tokens SET used_at = NOW()
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: ExpireEmailTokens :exec
+UPDATE email_tokens SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+-- name: DeleteUserEmailTokens :exec
+DELETE FROM email_tokens WHERE user_id = $1;
@@ -0,0 +1,27 @@
+-- name: CreateFilter :one
+INSERT INTO filters (id, created_at, updated_at, word, whole_word, action)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: ListFilters :many
+SELECT * FROM filters ORDER BY word;
+
+-- name: UpdateFilter :one
+UPDATE filters
+SET
+    updated_at = NOW(),
+    word = $2,
+    whole_word = $3,
+    action = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFilter :execrows
+DELETE FROM filters WHERE id = $1;
@@ -0,0 +1,23 @@
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (key, failures, last_failure_at)
+VALUES (sqlc.arg('key'), 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failure_at < NOW() - sqlc.arg('window_seconds')::int * INTERVAL '1 second' THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING failures;
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = NOW() + sqlc.arg('seconds')::int * INTERVAL '1 second'
+WHERE key = sqlc.arg('key');
+
+-- name: GetLoginRetryAfter :one
+SELECT COALESCE(MAX(CEIL(EXTRACT(EPOCH FROM locked_until - NOW()))), 0)::int AS retry_after
+FROM login_throttles
+WHERE key = ANY(sqlc.arg('keys')::text[]) AND locked_until > NOW();
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_throttles WHERE key = ANY(sqlc.arg('keys')::text[]);
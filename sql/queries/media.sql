@@ -0,0 +1,52 @@
+-- name: CreateMediaUpload :one
+INSERT INTO media_uploads (id, created_at, user_id, content_type, size_bytes, storage_key, thumbnail_key, width, height)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetMediaUsageByUserID :one
+SELECT COALESCE(SUM(size_bytes), 0)::bigint AS total FROM media_uploads
+WHERE user_id = $1;
+
+-- name: CountAttachableMedia :one
+SELECT COUNT(*) FROM media_uploads
+WHERE user_id = sqlc.arg('user_id') AND chirp_id IS NULL AND id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: AttachMediaToChirp :execrows
+UPDATE media_uploads
+SET chirp_id = sqlc.arg('chirp_id')::uuid, position = array_position(sqlc.arg('ids')::uuid[], id)
+WHERE user_id = sqlc.arg('user_id') AND chirp_id IS NULL AND id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: ListMediaByChirpIDs :many
+SELECT * FROM media_uploads
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY position;
+
+-- name: ListMediaByUserID :many
+SELECT * FROM media_uploads
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteUnattachedMedia :many
+DELETE FROM media_uploads
+WHERE user_id = $1 AND chirp_id IS NULL
+RETURNING *;
+
+-- name: DeleteChirpMedia :many
+DELETE FROM media_uploads
+WHERE chirp_id = $1
+RETURNING *;
+
+-- name: DeleteUserMedia :many
+DELETE FROM media_uploads
+WHERE user_id = $1
+RETURNING *;
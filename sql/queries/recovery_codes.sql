@@ -0,0 +1,14 @@
+-- name: AddRecoveryCodes :exec
+INSERT INTO recovery_codes (id, user_id, code_hash)
+SELECT gen_random_uuid(), sqlc.arg('user_id')::uuid, unnest(sqlc.arg('code_hashes')::text[]);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: ListUnusedRecoveryCodes :many
+SELECT * FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL;
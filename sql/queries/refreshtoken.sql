@@ -1,12 +1,16 @@
 -- name: InsertFreshToken :one
-INSERT INTO refreshtokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refreshtokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, user_agent, ip, last_used_at)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
     NOW() + INTERVAL '60 days',
-    NULL
+    NULL,
+    $3,
+    $4,
+    $5,
+    NOW()
 )
 RETURNING *;
 
@@ -22,4 +26,39 @@ SELECT * FROM refreshtokens WHERE token = $1;
 UPDATE refreshtokens
 SET revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
\ No newline at end of file
+WHERE token = $1;
+
+-- name: RotateRefreshToken :one
+-- Expiry was checked before rotating; testing it again here would make a
+-- token that expires in between look like a reused one.
+UPDATE refreshtokens
+SET revoked_at = NOW(),
+    updated_at = NOW(),
+    replaced_by = sqlc.arg('replaced_by')::text
+WHERE token = sqlc.arg('token') AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refreshtokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: ListUserSessions :many
+SELECT family_id, user_agent, ip, last_used_at, expires_at,
+    (SELECT MIN(f.created_at) FROM refreshtokens f WHERE f.family_id = refreshtokens.family_id)::timestamp AS signed_in_at
+FROM refreshtokens
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+ORDER BY last_used_at DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refreshtokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refreshtokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
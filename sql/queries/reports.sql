@@ -0,0 +1,46 @@
+-- name: CreateChirpReport :one
+INSERT INTO chirp_reports (id, created_at, chirp_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: ListReportQueue :many
+SELECT
+    chirps.id AS chirp_id,
+    chirps.user_id AS author_id,
+    chirps.body,
+    chirps.hidden_at,
+    COUNT(*) AS report_count,
+    string_agg(DISTINCT chirp_reports.reason, ',' ORDER BY chirp_reports.reason)::text AS reasons,
+    MIN(chirp_reports.created_at)::timestamp AS first_reported_at,
+    MAX(chirp_reports.created_at)::timestamp AS last_reported_at
+FROM chirp_reports
+JOIN chirps ON chirps.id = chirp_reports.chirp_id
+WHERE chirp_reports.resolved_at IS NULL
+  AND chirps.deleted_at IS NULL
+GROUP BY chirps.id
+ORDER BY report_count DESC, first_reported_at, chirps.id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ResolveChirpReports :execrows
+UPDATE chirp_reports
+SET resolved_at = NOW(), resolution = $2
+WHERE chirp_id = $1 AND resolved_at IS NULL;
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, created_at, moderator_id, chirp_id, author_id, action, note)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
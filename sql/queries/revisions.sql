@@ -0,0 +1,18 @@
+-- name: EditChirp :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, created_at, chirp_id, body, original_body)
+    SELECT gen_random_uuid(), NOW(), chirps.id, chirps.body, chirps.original_body
+    FROM chirps
+    WHERE chirps.id = $1
+)
+UPDATE chirps
+SET
+    body = $2,
+    original_body = $3,
+    edited_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListChirpRevisions :many
+SELECT * FROM chirp_revisions WHERE chirp_id = $1 ORDER BY created_at DESC;
@@ -0,0 +1,46 @@
+-- name: AddChirpTags :exec
+INSERT INTO chirp_tags (chirp_id, tag, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, unnest(sqlc.arg('tags')::text[]), sqlc.arg('created_at')::timestamp
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpTags :exec
+DELETE FROM chirp_tags WHERE chirp_id = $1;
+
+-- name: ListChirpsByTag :many
+SELECT chirps.* FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+WHERE chirp_tags.tag = sqlc.arg('tag')
+  AND chirps.tombstoned_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: TrendingTags :many
+SELECT chirp_tags.tag, COUNT(*) AS chirp_count
+FROM chirp_tags
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirp_tags.created_at > NOW() - sqlc.arg('window_seconds')::int * INTERVAL '1 second'
+  AND chirps.deleted_at IS NULL
+GROUP BY chirp_tags.tag
+ORDER BY chirp_count DESC, chirp_tags.tag
+LIMIT sqlc.arg('limit');
+
+-- name: AddChirpMentions :exec
+-- Mentions resolve by handle only; matching emails would reveal which
+-- addresses have accounts.
+INSERT INTO chirp_mentions (chirp_id, user_id, mention)
+SELECT sqlc.arg('chirp_id')::uuid, users.id, users.handle
+FROM users
+WHERE users.handle IN (SELECT lower(mention) FROM unnest(sqlc.arg('mentions')::text[]) AS mention)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1;
+
+-- name: ListMentionsByChirpIDs :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY mention;
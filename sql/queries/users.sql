@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, hashed_password, email)
+INSERT INTO users (id, created_at, updated_at, hashed_password, email, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -19,6 +20,7 @@ SELECT * FROM users WHERE email = $1;
 UPDATE users
 SET
     updated_at = NOW(),
+    email_verified_at = CASE WHEN email = $2 THEN email_verified_at END,
     email = $2,
     hashed_password = $3
 WHERE id = $1
@@ -30,4 +32,150 @@ SET
     updated_at = NOW(),
     is_chirpy_red = TRUE
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: SetPendingTOTPSecret :exec
+UPDATE users
+SET
+    updated_at = NOW(),
+    totp_secret = $2,
+    totp_enabled = FALSE
+WHERE id = $1;
+
+-- name: EnableTOTP :exec
+UPDATE users
+SET
+    updated_at = NOW(),
+    totp_enabled = TRUE,
+    totp_last_step = $2
+WHERE id = $1;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET
+    updated_at = NOW(),
+    totp_secret = NULL,
+    totp_enabled = FALSE,
+    totp_last_step = 0
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1 AND totp_last_step < $2;
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET
+    updated_at = NOW(),
+    email_verified_at = NOW()
+WHERE id = $1 AND email = $2;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET
+    updated_at = NOW(),
+    hashed_password = $2
+WHERE id = $1;
+
+-- name: PatchUser :one
+UPDATE users
+SET
+    updated_at = NOW(),
+    email_verified_at = CASE WHEN sqlc.narg('email')::text IS NULL OR email = sqlc.narg('email') THEN email_verified_at END,
+    email = COALESCE(sqlc.narg('email'), email),
+    hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url)
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: GetUserProfileByHandle :one
+SELECT
+    users.id,
+    users.created_at,
+    users.handle,
+    users.display_name,
+    users.bio,
+    users.avatar_url,
+    (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.tombstoned_at IS NULL AND chirps.hidden_at IS NULL AND chirps.deleted_at IS NULL) AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count
+FROM users
+WHERE users.handle = $1 AND users.deleted_at IS NULL;
+
+-- name: ListAuthorsByIDs :many
+SELECT id, handle, display_name, avatar_url FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: AnonymizeUser :exec
+UPDATE users
+SET
+    updated_at = NOW(),
+    deleted_at = NOW(),
+    email = 'deleted-' || id::text || '@deleted.invalid',
+    hashed_password = '',
+    handle = 'deleted_' || left(replace(id::text, '-', ''), 12),
+    display_name = '',
+    bio = '',
+    avatar_url = '',
+    is_chirpy_red = false,
+    totp_secret = NULL,
+    totp_enabled = false,
+    email_verified_at = NULL
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: PromoteFirstAdmin :execrows
+UPDATE users
+SET role = 'admin', updated_at = NOW()
+WHERE email = $1
+  AND deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin');
+
+-- name: GetUserStatus :one
+SELECT suspended_at, deleted_at, role FROM users WHERE id = $1;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg('email')::text IS NULL OR email ILIKE '%' || sqlc.narg('email')::text || '%')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = NOW(), suspended_reason = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, suspended_reason = '', updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetUserRed :one
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: ClearUserPassword :exec
+UPDATE users
+SET hashed_password = '', updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE filters (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    word TEXT NOT NULL UNIQUE,
+    whole_word BOOLEAN NOT NULL DEFAULT FALSE,
+    action TEXT NOT NULL DEFAULT 'replace' CHECK (action IN ('replace', 'reject'))
+);
+INSERT INTO filters (id, created_at, updated_at, word, whole_word, action) VALUES
+    (gen_random_uuid(), NOW(), NOW(), 'kerfuffle', FALSE, 'replace'),
+    (gen_random_uuid(), NOW(), NOW(), 'sharbert', FALSE, 'replace'),
+    (gen_random_uuid(), NOW(), NOW(), 'fornax', FALSE, 'replace');
+
+ALTER TABLE chirps ADD COLUMN original_body TEXT;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN original_body;
+DROP TABLE filters;
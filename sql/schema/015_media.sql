@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE media_uploads (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Uploads start unattached and are linked when a chirp is posted.
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    position INTEGER,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL
+);
+CREATE INDEX media_uploads_user_id_idx ON media_uploads (user_id);
+CREATE INDEX media_uploads_chirp_id_idx ON media_uploads (chirp_id);
+
+-- +goose Down
+DROP TABLE media_uploads;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- Every refresh token issued by rotation shares the family of the login that
+-- started the chain, so a stolen token can be cut off along with its heirs.
+ALTER TABLE refreshtokens ADD COLUMN family_id UUID;
+UPDATE refreshtokens SET family_id = gen_random_uuid();
+ALTER TABLE refreshtokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refreshtokens ADD COLUMN replaced_by TEXT;
+CREATE INDEX refreshtokens_family_id_idx ON refreshtokens (family_id);
+
+CREATE TABLE security_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    event TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    detail TEXT NOT NULL
+);
+CREATE INDEX security_events_user_id_created_at_idx ON security_events (user_id, created_at);
+
+-- +goose Down
+DROP TABLE security_events;
+DROP INDEX refreshtokens_family_id_idx;
+ALTER TABLE refreshtokens DROP COLUMN replaced_by;
+ALTER TABLE refreshtokens DROP COLUMN family_id;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE refreshtokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE refreshtokens ADD COLUMN last_used_at TIMESTAMP;
+UPDATE refreshtokens SET last_used_at = updated_at;
+ALTER TABLE refreshtokens ALTER COLUMN last_used_at SET NOT NULL;
+CREATE INDEX refreshtokens_user_id_idx ON refreshtokens (user_id);
+
+-- +goose Down
+DROP INDEX refreshtokens_user_id_idx;
+ALTER TABLE refreshtokens DROP COLUMN last_used_at;
+ALTER TABLE refreshtokens DROP COLUMN ip;
+ALTER TABLE refreshtokens DROP COLUMN user_agent;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN handle TEXT,
+    ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+    ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+UPDATE users SET handle = 'user_' || left(replace(id::text, '-', ''), 12);
+ALTER TABLE users
+    ALTER COLUMN handle SET NOT NULL,
+    ADD CONSTRAINT users_handle_key UNIQUE (handle);
+
+UPDATE chirp_mentions SET mention = users.handle
+FROM users
+WHERE users.id = chirp_mentions.user_id;
+
+-- +goose Down
+UPDATE chirp_mentions SET mention = users.email
+FROM users
+WHERE users.id = chirp_mentions.user_id;
+
+ALTER TABLE users
+    DROP COLUMN avatar_url,
+    DROP COLUMN bio,
+    DROP COLUMN display_name,
+    DROP COLUMN handle;
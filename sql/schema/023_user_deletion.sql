@@ -0,0 +1,7 @@
+-- +goose Up
+-- Set when an account is deleted under the anonymize policy; the row stays
+-- behind, scrubbed, so the user's chirps keep an author.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN deleted_at;
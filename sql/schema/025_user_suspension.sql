@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN suspended_at TIMESTAMP,
+    ADD COLUMN suspended_reason TEXT NOT NULL DEFAULT '';
+CREATE INDEX users_created_at_id_idx ON users (created_at, id);
+
+-- +goose Down
+DROP INDEX users_created_at_id_idx;
+ALTER TABLE users
+    DROP COLUMN suspended_reason,
+    DROP COLUMN suspended_at;
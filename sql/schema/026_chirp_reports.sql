@@ -0,0 +1,33 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE chirp_reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'misinformation', 'other')),
+    details TEXT NOT NULL,
+    resolved_at TIMESTAMP,
+    resolution TEXT
+);
+-- One open report per user and chirp; a dismissed chirp can be reported again.
+CREATE UNIQUE INDEX chirp_reports_open_idx ON chirp_reports (chirp_id, reporter_id) WHERE resolved_at IS NULL;
+CREATE INDEX chirp_reports_unresolved_idx ON chirp_reports (created_at) WHERE resolved_at IS NULL;
+
+-- No foreign key on chirp_id: the record outlives a deleted chirp.
+CREATE TABLE moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    chirp_id UUID NOT NULL,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL CHECK (action IN ('dismiss', 'hide', 'delete', 'suspend_author')),
+    note TEXT NOT NULL
+);
+CREATE INDEX moderation_actions_chirp_id_idx ON moderation_actions (chirp_id);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE chirp_reports;
+ALTER TABLE chirps DROP COLUMN hidden_at;
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+var handleRe = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// Handles that would be shadowed by fixed routes under /api/users/.
+var reservedHandles = []string{"export"}
+
+// normalizeHandle lower-cases a handle and strips a leading @, reporting
+// whether the result is a valid handle.
+func normalizeHandle(handle string) (string, bool) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	return handle, handleRe.MatchString(handle) && !slices.Contains(reservedHandles, handle)
+}
+
+// defaultHandle is given to users who sign up without choosing a handle.
+func defaultHandle() string {
+	return "user_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+}
+
+func validateDisplayName(name string) error {
+	if utf8.RuneCountInString(name) > maxDisplayNameLength {
+		return errors.New("display name must be at most 50 characters")
+	}
+	return nil
+}
+
+func validateBio(bio string) error {
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return errors.New("bio must be at most 160 characters")
+	}
+	return nil
+}
+
+// validateAvatarURL accepts an absolute http(s) URL, or an empty string to
+// clear the avatar.
+func validateAvatarURL(avatarurl string) error {
+	if len(avatarurl) == 0 {
+		return nil
+	}
+	if len(avatarurl) > maxAvatarURLLength {
+		return errors.New("avatar URL is too long")
+	}
+	parsed, err := url.Parse(avatarurl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return errors.New("avatar URL must be an http or https URL")
+	}
+	return nil
+}